@@ -0,0 +1,60 @@
+// Expands ranges in range notation (see truco.ParseRange), and plays them against each other.
+//
+// Usage:
+//
+//	go run ./cmd/range [-k "3o 4c"] [-m 4c] [-second] "1e 3+" ["7e 2 env"]
+//
+// With one range, lists its hands. With two, prints the equity of the first against the second.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"truco/pkg/truco"
+)
+
+func main() {
+	kCardsStr := flag.String("k", "", "known cards, that no range can hold")
+	muestraStr := flag.String("m", "", "muestra, for uruguayan truco")
+	isSecond := flag.Bool("second", false, "first range plays second")
+	flag.Parse()
+
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	kCards := []truco.Card(truco.NewHand(*kCardsStr))
+	muestra := truco.NO_CARD
+	if *muestraStr != "" {
+		muestra = truco.NewCard(*muestraStr)
+		kCards = append(kCards, muestra)
+	}
+
+	mRange, err := truco.ParseRange(flag.Arg(0), kCards)
+	if err != nil {
+		fmt.Println("Error parsing range:", err)
+		os.Exit(1)
+	}
+
+	if flag.NArg() == 1 {
+		for _, h := range mRange {
+			fmt.Printf("%s\t%.2f\n", strings.TrimSpace(h.Hand.ToString()), h.Weight)
+		}
+		fmt.Println(len(mRange), "hands")
+		return
+	}
+
+	oRange, err := truco.ParseRange(flag.Arg(1), kCards)
+	if err != nil {
+		fmt.Println("Error parsing range:", err)
+		os.Exit(1)
+	}
+
+	eq := truco.RangeEquity(mRange, oRange, muestra, !*isSecond)
+	fmt.Printf("%d hands vs %d hands\n", len(mRange), len(oRange))
+	fmt.Printf("Truco:  %.1f%%\n", eq.TrucoEquity*100)
+	fmt.Printf("Envido: %.1f%%\n", eq.EnvidoEquity*100)
+}
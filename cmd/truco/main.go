@@ -4,9 +4,11 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"truco/internal/server"
+	"truco/pkg/fsm"
 	"truco/pkg/truco"
 )
 
@@ -47,6 +49,11 @@ func main() {
 		log.Fatalf("Error parsing templates: %v", err)
 	}
 
+	// Sign match states, so shared links can't be edited
+	if key := os.Getenv("TRUCO_STATE_KEY"); key != "" {
+		fsm.SetStateKey([]byte(key))
+	}
+
 	// Initialize Server
 	srv := server.NewServer(tmpl)
 
@@ -0,0 +1,66 @@
+package pages
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"truco/pkg/truco"
+)
+
+// Max hands listed in the results, aggregates use every matching hand
+const EXPLORE_LIMIT = 200
+
+type ExploreHandler struct {
+	Tmpl *template.Template
+}
+
+func NewExploreHandler(tmpl *template.Template) *ExploreHandler {
+	return &ExploreHandler{Tmpl: tmpl}
+}
+
+func (h *ExploreHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		h.handleQuery(w, r)
+		return
+	}
+
+	if err := h.Tmpl.ExecuteTemplate(w, "explore.html", nil); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (h *ExploreHandler) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form", http.StatusBadRequest)
+		return
+	}
+
+	data := struct {
+		Query  string
+		Error  string
+		Result truco.QueryResult
+		Limit  int
+	}{
+		Query: r.Form.Get("query"),
+		Limit: EXPLORE_LIMIT,
+	}
+
+	// invalid queries are shown to the user, next to the input
+	q, err := truco.ParseQuery(data.Query)
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		records, err := truco.LoadHandRecords("web/static/hand_stats.csv")
+		if err != nil {
+			http.Error(w, "Failed to load hands: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		data.Result = truco.RunQuery(records, q, EXPLORE_LIMIT)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := h.Tmpl.ExecuteTemplate(w, "explore_results.html", data); err != nil {
+		log.Printf("Template execution error: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"strconv"
 	"truco/pkg/truco"
 )
 
@@ -53,12 +52,17 @@ func (h *HomeHandler) handleCalculate(w http.ResponseWriter, r *http.Request) {
 	mode := r.Form.Get("mode")
 	muestraStr := r.Form.Get("muestra")
 	isMHandFirst := r.Form.Get("isMHandFirst") == "true"
-	hasStrategy := r.Form.Get("hasStrategy") == "true"
+	model, ok := truco.OPPONENT_MODELS[r.Form.Get("model")]
+	if !ok {
+		model = truco.ReasonableOpponent{}
+	}
 	sonBuenas := r.Form.Get("sonBuenas") == "true"
 	flor := r.Form.Get("flor") == "true"
-	kEnvido, err := strconv.Atoi(r.Form.Get("envido"))
-	if err != nil {
-		kEnvido = 255
+	// a declared number, or the text format of truco.EnvidoConstraint.
+	// Nothing declared: can't hold flor, it must be declared
+	kEnvido, err := truco.ParseEnvidoConstraint(r.Form.Get("envido"))
+	if err != nil || kEnvido.IsAny() {
+		kEnvido = truco.EnvidoDeclined()
 	}
 
 	mHand := truco.NewHand(mHandStr)
@@ -68,34 +72,61 @@ func (h *HomeHandler) handleCalculate(w http.ResponseWriter, r *http.Request) {
 	}
 	kCards := []truco.Card(truco.NewHand(kCardsStr))
 
-	var muestra truco.Card
-	if mode == "UY" {
-		if muestraStr == "" {
-			http.Error(w, "Select a muestra", http.StatusBadRequest)
-			return
-		}
+	if mode == "" {
+		mode = "AR"
+	}
+	muestra := truco.NO_CARD
+	if muestraStr != "" {
 		muestra = truco.NewHand(muestraStr)[0]
 	}
+	rules, err := truco.NewRuleset(mode, muestra)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	if sonBuenas {
-		if mode == "UY" {
-			kEnvido = 100 + int(mHand.EnvidoUY(muestra))
-		} else {
-			kEnvido = 100 + int(mHand.Envido())
+		kEnvido = truco.EnvidoAtMost(rules.Envido(mHand))
+	} else if flor && rules.HasFlor() {
+		kEnvido = truco.EnvidoFlor()
+	}
+
+	stats := mHand.StrengthStats(rules, kCards, []truco.Card{}, kEnvido, isMHandFirst, model)
+	strategy := mHand.Strategy(rules, kCards, []truco.Card{}, kEnvido, isMHandFirst)
+
+	// equity against the range the user gave for the opponent
+	var rangeEquity *truco.EquityMatrix
+	if oRangeStr := r.Form.Get("oRange"); oRangeStr != "" {
+		// the opponent can't hold my cards, and must hold the cards it played (kCards)
+		known := truco.CardsExcluding(truco.ALL_CARDS, rules.Deck())
+		known = append(known, mHand...)
+		parsed, err := truco.ParseRange(oRangeStr, known)
+		if err != nil {
+			http.Error(w, "Invalid range: "+err.Error(), http.StatusBadRequest)
+			return
 		}
-	} else if flor {
-		kEnvido = 200
+		oRange := make(truco.Range, 0, len(parsed))
+		for _, h := range parsed {
+			if h.Hand.HasAll(kCards) {
+				oRange = append(oRange, h)
+			}
+		}
+		eq := truco.RangeEquityRules(rules, truco.NewRange([]truco.Hand{mHand}, []truco.Card{}), oRange, isMHandFirst)
+		rangeEquity = &eq
 	}
 
-	var stats truco.TrucoStats
-	if mode == "UY" {
-		stats = mHand.TrucoStrengthStatsUY(kCards, []truco.Card{muestra}, uint8(kEnvido), isMHandFirst, hasStrategy)
-	} else {
-		stats = mHand.TrucoStrengthStats(kCards, []truco.Card{}, uint8(kEnvido), isMHandFirst, hasStrategy)
+	data := struct {
+		truco.TrucoStats
+		Strategy    truco.StrategyStats
+		RangeEquity *truco.EquityMatrix
+	}{
+		TrucoStats:  stats,
+		Strategy:    strategy,
+		RangeEquity: rangeEquity,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := h.Tmpl.ExecuteTemplate(w, "results_partial.html", stats); err != nil {
+	if err := h.Tmpl.ExecuteTemplate(w, "results_partial.html", data); err != nil {
 		log.Printf("Template execution error: %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
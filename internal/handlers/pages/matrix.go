@@ -3,6 +3,7 @@ package pages
 import (
 	"html/template"
 	"net/http"
+	"strconv"
 	"truco/internal/handlers/partials"
 	"truco/pkg/fsm"
 	"truco/pkg/truco"
@@ -31,24 +32,51 @@ func (h *MatrixHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// }
 
 	// First default action
-	match := fsm.NewMatch()
+	match, err := partials.GetHouseMatch(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	trackerData := partials.TrackerData{
 		ActionTitle: "Jugador 1",
 		Actions:     match.ValidActions(),
+		Raises:      partials.TrucoRaises(match),
 		State:       string(match.Encode()),
 	}
 
 	// Initial load of all cards
-	cards := partials.GetAvailableCards(truco.FilterHands{})
+	cards := partials.GetAvailableCards(match, fsm.PLAY)
+
+	// seats to choose the point of view of the tracker
+	seats := make([]partials.SeatUI, 0, fsm.NUM_PLAYERS)
+	for s := range uint8(fsm.NUM_PLAYERS) {
+		seats = append(seats, partials.SeatUI{Player: s + 1, Team: s%2 + 1})
+	}
 
 	data := struct {
 		// Stats   template.JS
 		Tracker partials.TrackerData
 		Cards   []partials.CardUI
+		Seats   []partials.SeatUI
+		House   fsm.HouseRules
+		Scores  [2]uint8
+		Mode    string
+		Muestra string
+		Eleven  string // team at 11 points in a hand of eleven, empty if none
 	}{
 		// Stats:   template.JS(statsJSON),
 		Tracker: trackerData,
 		Cards:   cards,
+		Seats:   seats,
+		House:   match.House,
+		Scores:  match.Scores,
+		Mode:    match.Rules.Name(),
+	}
+	if m := match.Rules.Muestra(); m != truco.NO_CARD {
+		data.Muestra = m.ToString()
+	}
+	if match.Eleven {
+		data.Eleven = strconv.Itoa(int(match.CTrucoAsk+1) % 2)
 	}
 
 	if err := h.Tmpl.ExecuteTemplate(w, "index_matrix.html", data); err != nil {
@@ -7,16 +7,23 @@ import (
 )
 
 func (h *Handler) GetCards(w http.ResponseWriter, r *http.Request) {
-	match := GetMatch(r)
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+	action := fsm.PLAY
+	if fsm.ValidAction(r.URL.Query().Get("action")) == fsm.SHOW {
+		action = fsm.SHOW
+	}
 
 	data := struct {
 		Cards  []CardUI
 		State  string
 		Action fsm.ValidAction
 	}{
-		Cards:  GetAvailableCards(match.GetStatsFilter()),
+		Cards:  GetAvailableCards(match, action),
 		State:  string(match.Encode()),
-		Action: fsm.PLAY,
+		Action: action,
 	}
 
 	err := h.tmpl.ExecuteTemplate(w, "cards", data)
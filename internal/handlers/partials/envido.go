@@ -0,0 +1,92 @@
+package partials
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"truco/pkg/truco"
+)
+
+type EnvidoHistogramUI struct {
+	Player uint8
+	Count  int
+	Bars   []EnvidoBarUI
+	First  string // label of the lowest envido
+	Last   string // label of the highest envido
+}
+
+type EnvidoBarUI struct {
+	Label  string  // envido, or flor
+	P      float32 // probability of this envido
+	Height float32 // height of the bar, relative to the highest bar
+	IsFlor bool
+}
+
+// Distribution of the envido of the rivals of the viewer, in the rules of the match
+// (flor buckets with a muestra, none without envido).
+//
+// Query params:
+//   - state: encoded match
+//   - viewer: optional, seat of the viewer (see GetViewFilters)
+//   - hand: optional, cards of the viewer, defaults to their private hand (see fsm.Match.SetPrivateHands)
+func (h *Handler) TrackEnvido(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+
+	// the rivals can't hold the hands the viewer knows: theirs, and their partner's
+	view := GetViewFilters(r, match)
+	seats := match.SeatViewsOf(view.Viewer)
+	if mHand := truco.NewHand(query.Get("hand")); len(mHand) == 3 {
+		seats[view.Viewer].Hand = mHand
+	}
+	known := make([]truco.Card, 0, 6)
+	for _, seat := range seats {
+		known = append(known, seat.Hand...)
+	}
+
+	histograms := make([]EnvidoHistogramUI, 0, 2)
+	rivals := match.RivalsOf(view.Viewer)
+	if match.Rules.Bets().Envido == 0 {
+		rivals = nil
+	}
+	for _, rival := range rivals {
+		filter := match.GetPlayerFilter(rival)
+		kCards := append(slices.Clone(filter.KCards), known...)
+		hist := truco.EnvidoDistributionRules(match.Rules, filter.MEnvido, filter.MCards, kCards)
+		histograms = append(histograms, newEnvidoHistogramUI(rival+1, hist))
+	}
+
+	err := h.tmpl.ExecuteTemplate(w, "envido_histogram", histograms)
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func newEnvidoHistogramUI(player uint8, hist truco.EnvidoHistogram) EnvidoHistogramUI {
+	var maxP float32
+	for _, b := range hist.Buckets {
+		maxP = max(maxP, b.P)
+	}
+
+	ui := EnvidoHistogramUI{Player: player, Count: hist.Count}
+	for _, b := range hist.Buckets {
+		bar := EnvidoBarUI{
+			Label:  strconv.Itoa(int(b.Envido)),
+			P:      b.P,
+			Height: b.P / maxP,
+		}
+		if b.Envido > 200 {
+			bar.Label = "Flor " + strconv.Itoa(int(b.Envido)-200)
+			bar.IsFlor = true
+		}
+		ui.Bars = append(ui.Bars, bar)
+	}
+	if len(ui.Bars) > 0 {
+		ui.First = ui.Bars[0].Label
+		ui.Last = ui.Bars[len(ui.Bars)-1].Label
+	}
+	return ui
+}
@@ -0,0 +1,71 @@
+package partials
+
+import (
+	"net/http"
+	"truco/pkg/truco"
+)
+
+// Deals sampled for the card locations
+const LOCATION_SAMPLES = 3000
+
+type CardHeatmapUI struct {
+	Seats []SeatUI
+	Rows  []CardHeatmapRowUI
+}
+
+type SeatUI struct {
+	Player uint8
+	Team   uint8 // 2v2: players 1 and 3 against players 2 and 4
+	IsMe   bool
+}
+
+type CardHeatmapRowUI struct {
+	Card string
+	P    []float32 // per seat
+}
+
+// Chance of every seat holding each card, from what the table knows
+// and the hand of the viewer.
+//
+// Query params:
+//   - state: encoded match
+//   - viewer: optional, seat of the viewer (see GetViewFilters)
+//   - hand: optional, cards of the viewer, defaults to their private hand (see fsm.Match.SetPrivateHands)
+//
+// Cards are dealt in the rules of the match.
+func (h *Handler) TrackLocations(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+
+	viewer := GetViewFilters(r, match).Viewer
+	seats := match.SeatViewsOf(viewer)
+	if mHand := truco.NewHand(query.Get("hand")); len(mHand) == 3 {
+		seats[viewer].Hand = mHand
+	}
+
+	loc, err := truco.CardLocationsRules(match.Rules, seats, LOCATION_SAMPLES, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ui := CardHeatmapUI{}
+	for s := range seats {
+		ui.Seats = append(ui.Seats, SeatUI{
+			Player: uint8(s) + 1,
+			Team:   uint8(s)%2 + 1,
+			IsMe:   uint8(s) == viewer,
+		})
+	}
+	for i, c := range loc.Cards {
+		ui.Rows = append(ui.Rows, CardHeatmapRowUI{Card: c.ToString(), P: loc.P[i]})
+	}
+
+	err = h.tmpl.ExecuteTemplate(w, "card_heatmap", ui)
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -1,8 +1,10 @@
 package partials
 
 import (
+	"fmt"
 	"html/template"
 	"net/http"
+	"strconv"
 	"truco/pkg/fsm"
 	"truco/pkg/truco"
 )
@@ -10,10 +12,12 @@ import (
 type TrackerData struct {
 	ActionTitle string
 	Actions     []fsm.ValidAction
+	Raises      map[fsm.ValidAction]bool // actions of Actions that raise the truco bet, see TrucoRaises
 	DoneActions []fsm.ValidAction
 	State       string
 	PlayedCard  string
 	Stats       template.JS
+	Liars       []uint8 // players that lied about their envido (1-4), see fsm.Match.Lied
 }
 
 type Handler struct {
@@ -25,14 +29,127 @@ func NewHandler(tmpl *template.Template) *Handler {
 }
 
 // Returns a fsm.Match object from the state in queryparams,
-// or a new empty fsm.Match
-func GetMatch(r *http.Request) *fsm.Match {
+// or a new empty fsm.Match.
+//
+// Returns an error if the state is not valid or was edited (see fsm.Decode)
+func GetMatch(r *http.Request) (*fsm.Match, error) {
 	stateParam := r.URL.Query().Get("state")
 	if stateParam == "" {
-		return fsm.NewMatch()
-	} else {
-		return fsm.Decode([]byte(stateParam))
+		return fsm.NewMatch(fsm.DEFAULT_HOUSE_RULES), nil
 	}
+	return fsm.Decode([]byte(stateParam))
+}
+
+// Returns the match of the state in queryparams, as GetMatch.
+//
+// If the state is not valid, answers a bad request and returns false: the handler is done
+func GetMatchOrError(w http.ResponseWriter, r *http.Request) (*fsm.Match, bool) {
+	match, err := GetMatch(r)
+	if err != nil {
+		http.Error(w, "Invalid state: "+err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return match, true
+}
+
+// Returns a new fsm.Match with the rules and house rules in queryparams,
+// or the defaults if the settings were not sent (house is empty):
+//   - mode: AR (default), UY or BR (see truco.NewRuleset)
+//   - muestra: card turned up, for UY and BR
+//   - flor, flor_mandatory, contraflor_resto, envido_first, ley_falta, son_buenas, lie_penalty: "true" to enable
+//   - points: 15 or 30
+//   - score0, score1: points of each team before the hand
+//   - eleven: optional, team (0-1) that plays a hand of eleven, for BR (see fsm.Match.SetEleven)
+//
+// Returns an error if the rules or the house rules can't be played (see fsm.HouseRules.Validate)
+func GetHouseMatch(r *http.Request) (*fsm.Match, error) {
+	q := r.URL.Query()
+	if q.Get("house") == "" {
+		return fsm.NewMatch(fsm.DEFAULT_HOUSE_RULES), nil
+	}
+
+	mode := q.Get("mode")
+	if mode == "" {
+		mode = "AR"
+	}
+	muestra := truco.NO_CARD
+	if q.Get("muestra") != "" {
+		muestra = truco.NewCard(q.Get("muestra"))
+	}
+	rules, err := truco.NewRuleset(mode, muestra)
+	if err != nil {
+		return nil, err
+	}
+
+	points, _ := strconv.Atoi(q.Get("points"))
+	house := fsm.HouseRules{
+		Flor:              q.Get("flor") == "true",
+		FlorMandatory:     q.Get("flor_mandatory") == "true",
+		ContraflorAlResto: q.Get("contraflor_resto") == "true",
+		EnvidoFirstRound:  q.Get("envido_first") == "true",
+		LeyDeLaFalta:      q.Get("ley_falta") == "true",
+		ForcedSonBuenas:   q.Get("son_buenas") == "true",
+		LiePenalty:        q.Get("lie_penalty") == "true",
+		Points:            uint8(min(max(points, 0), 255)),
+	}
+	if err := house.Validate(); err != nil {
+		return nil, err
+	}
+
+	match := fsm.NewMatchRules(rules, house)
+	score0, _ := strconv.Atoi(q.Get("score0"))
+	score1, _ := strconv.Atoi(q.Get("score1"))
+	if score0 < 0 || score1 < 0 {
+		return nil, fmt.Errorf("Scores can't be negative")
+	}
+	if err := match.SetScores(uint8(min(score0, 255)), uint8(min(score1, 255))); err != nil {
+		return nil, err
+	}
+	if q.Get("eleven") != "" {
+		team, err := strconv.Atoi(q.Get("eleven"))
+		if err != nil || team < 0 {
+			return nil, fmt.Errorf("Invalid team for the hand of eleven")
+		}
+		if err := match.SetEleven(uint8(min(team, 255))); err != nil {
+			return nil, err
+		}
+	}
+	return match, nil
+}
+
+// Returns the filters of the tracker for the point of view in queryparams:
+//   - viewer: seat the tracker is seen from (0-3), defaults to the current player
+//   - seat: seat whose hands to show (0-3), defaults to the viewer
+func GetViewFilters(r *http.Request, match *fsm.Match) fsm.ViewFilters {
+	viewer := parseSeat(r.URL.Query().Get("viewer"), match.CPlayer)
+	seat := parseSeat(r.URL.Query().Get("seat"), viewer)
+	return match.GetViewFilters(viewer, seat)
+}
+
+func parseSeat(s string, def uint8) uint8 {
+	seat, err := strconv.Atoi(s)
+	if err != nil || seat < 0 || seat >= fsm.NUM_PLAYERS {
+		return def
+	}
+	return uint8(seat)
+}
+
+// Returns the players that lied about their envido, numbered from 1
+func GetLiars(match *fsm.Match) []uint8 {
+	var liars []uint8
+	for _, p := range match.Liars() {
+		liars = append(liars, p+1)
+	}
+	return liars
+}
+
+// Returns the actions that raise the truco bet in the rules of match, as a set for the templates
+func TrucoRaises(match *fsm.Match) map[fsm.ValidAction]bool {
+	raises := make(map[fsm.ValidAction]bool)
+	for _, raise := range match.TrucoRaises() {
+		raises[raise] = true
+	}
+	return raises
 }
 
 // Derived from truco.Card.
@@ -43,20 +160,18 @@ type CardUI struct {
 	OK bool
 }
 
-// Returns a copy of ALL_CARDS, excluding cards in
-// filter.MCards and filter.KCards
-func GetAvailableCards(filter truco.FilterHands) []CardUI {
-	excluded := make(map[truco.Card]bool, len(filter.KCards)*2)
-	for _, c := range filter.KCards {
-		excluded[c] = true
-	}
-	for _, c := range filter.MCards {
-		excluded[c] = true
+// Returns a copy of ALL_CARDS, marking as not OK
+// the cards the current player can't play (see fsm.Match.ValidateCard),
+// or the envido winner can't show (action fsm.SHOW, see fsm.Match.ValidateShow)
+func GetAvailableCards(match *fsm.Match, action fsm.ValidAction) []CardUI {
+	validate := match.ValidateCard
+	if action == fsm.SHOW {
+		validate = match.ValidateShow
 	}
 
 	res := make([]CardUI, 0, len(truco.ALL_CARDS))
 	for _, c := range truco.ALL_CARDS {
-		res = append(res, CardUI{Card: c, OK: !excluded[c]})
+		res = append(res, CardUI{Card: c, OK: validate(c) == nil})
 	}
 	return res
 }
@@ -0,0 +1,88 @@
+package partials
+
+import (
+	"net/http"
+	"strconv"
+	"truco/pkg/fsm"
+	"truco/pkg/truco"
+)
+
+type MyStatsUI struct {
+	truco.TrucoStats
+	Player uint8
+	Rival  uint8
+}
+
+// Stores the hand of the user in the match, off the public history,
+// and returns the tracker of the same turn with the new state.
+//
+// Query params:
+//   - state: encoded match
+//   - seat: seat of the user (0-3)
+//   - hand: cards of the user, eg. "1e 7o 3c"
+//   - partner: optional, cards of the partner of the user
+func (h *Handler) TrackHand(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+
+	seat, err := strconv.Atoi(query.Get("seat"))
+	if err != nil || seat < 0 || seat >= fsm.NUM_PLAYERS {
+		http.Error(w, "Select your seat", http.StatusBadRequest)
+		return
+	}
+	hand := truco.NewHand(query.Get("hand"))
+	partner := truco.NewHand(query.Get("partner"))
+	if err := match.SetPrivateHands(uint8(seat), hand, partner); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = h.tmpl.ExecuteTemplate(w, "tracker", TrackerData{
+		ActionTitle: "Jugador " + string(rune('1'+match.CPlayer)),
+		Actions:     match.ValidActions(),
+		Raises:      TrucoRaises(match),
+		State:       string(match.Encode()),
+		Liars:       GetLiars(match),
+	})
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Strength of the private hand of the user, against the rival that plays right before them,
+// with everything the user knows so far. Nothing is shown without a private hand.
+//
+// Query params:
+//   - state: encoded match
+//   - model: optional, opponent model (see truco.OPPONENT_MODELS)
+func (h *Handler) TrackMyStats(w http.ResponseWriter, r *http.Request) {
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+	mHand, view, ok := match.GetPrivatePlayView()
+	if !ok {
+		return
+	}
+
+	model, ok := truco.OPPONENT_MODELS[r.URL.Query().Get("model")]
+	if !ok {
+		model = truco.ReasonableOpponent{}
+	}
+
+	// only the orders that start with the cards the user already played
+	stats := mHand.StrengthStatsPlayed(match.Rules, view.MPlayed, view.OPlayed, view.KCards, view.OEnvido, view.IsMHandFirst, model)
+
+	seat := match.Private.Seat
+	err := h.tmpl.ExecuteTemplate(w, "my_stats", MyStatsUI{
+		TrucoStats: stats,
+		Player:     seat + 1,
+		Rival:      (seat-1)%fsm.NUM_PLAYERS + 1,
+	})
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
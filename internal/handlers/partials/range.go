@@ -0,0 +1,75 @@
+package partials
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"truco/pkg/truco"
+)
+
+// Expands a range in range notation (see truco.ParseRange).
+//
+// Query params:
+//   - range: range notation, eg. "1e 3+, 7e 2 env"
+//   - kCards: optional, known cards that the range can't hold
+//   - vs: optional, a second range to get the equity against
+//   - mode: optional, AR (default), UY or BR (see truco.NewRuleset)
+//   - muestra: card turned up, for UY and BR
+//   - isMHandFirst: optional, range plays first against vs (default true)
+func (h *Handler) Range(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = "AR"
+	}
+	muestra := truco.NO_CARD
+	if query.Get("muestra") != "" {
+		muestra = truco.NewCard(query.Get("muestra"))
+	}
+	rules, err := truco.NewRuleset(mode, muestra)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// cards out of the deck of the rules (eg. the muestra) are never dealt
+	kCards := truco.CardsExcluding(truco.ALL_CARDS, rules.Deck())
+	kCards = append(kCards, truco.NewHand(query.Get("kCards"))...)
+
+	mRange, err := truco.ParseRange(query.Get("range"), kCards)
+	if err != nil {
+		http.Error(w, "Invalid range: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type rangeHand struct {
+		Hand   string  `json:"hand"`
+		Weight float64 `json:"weight"`
+	}
+	data := struct {
+		Hands        []rangeHand `json:"hands"`
+		TrucoEquity  *float32    `json:"truco_equity,omitempty"`
+		EnvidoEquity *float32    `json:"envido_equity,omitempty"`
+	}{
+		Hands: make([]rangeHand, 0, len(mRange)),
+	}
+	for _, wh := range mRange {
+		data.Hands = append(data.Hands, rangeHand{Hand: strings.TrimSpace(wh.Hand.ToString()), Weight: wh.Weight})
+	}
+
+	if query.Get("vs") != "" {
+		oRange, err := truco.ParseRange(query.Get("vs"), kCards)
+		if err != nil {
+			http.Error(w, "Invalid range: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		eq := truco.RangeEquityRules(rules, mRange, oRange, query.Get("isMHandFirst") != "false")
+		data.TrucoEquity = &eq.TrucoEquity
+		data.EnvidoEquity = &eq.EnvidoEquity
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		http.Error(w, "Failed to marshal range: "+err.Error(), http.StatusInternalServerError)
+	}
+}
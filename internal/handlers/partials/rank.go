@@ -0,0 +1,72 @@
+package partials
+
+import (
+	"net/http"
+	"truco/pkg/truco"
+)
+
+// Deals simulated for the chance of holding the best hand
+const RANK_SAMPLES = 4000
+
+type HandRankUI struct {
+	truco.HandRank
+	Tables []HandRankTableUI
+	Envido bool // false if the rules have no envido
+}
+
+type HandRankTableUI struct {
+	Players    int
+	BestTruco  float32
+	BestEnvido float32
+}
+
+// Ranks the hand of the viewer among the hands still possible.
+//
+// Query params:
+//   - state: encoded match
+//   - viewer: optional, seat of the viewer (see GetViewFilters)
+//   - hand: cards of the viewer, defaults to their private hand.
+//     Nothing is shown without them
+func (h *Handler) TrackRank(w http.ResponseWriter, r *http.Request) {
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+	view := GetViewFilters(r, match)
+	mHand := truco.NewHand(r.URL.Query().Get("hand"))
+	if len(mHand) == 0 && match.Private != nil && match.Private.Seat == view.Viewer {
+		mHand = truco.Hand(match.Private.Hand)
+	}
+	if len(mHand) != 3 {
+		return
+	}
+
+	// hands are ranked in the table of the rules, and never hold cards out of its deck (eg. the vira)
+	records, err := truco.LoadHandRecords(truco.HandStatsCSV(match.Rules))
+	if err != nil {
+		http.Error(w, "Failed to load hands: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	kCards := truco.CardsExcluding(truco.ALL_CARDS, match.Rules.Deck())
+	kCards = append(kCards, view.Mine.KCards...)
+	rank, err := truco.RankHand(mHand, kCards, records, RANK_SAMPLES, 1)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ui := HandRankUI{HandRank: rank, Envido: match.Rules.Bets().Envido > 0}
+	for i, players := range truco.TABLE_SIZES {
+		ui.Tables = append(ui.Tables, HandRankTableUI{
+			Players:    players,
+			BestTruco:  rank.BestTruco[i],
+			BestEnvido: rank.BestEnvido[i],
+		})
+	}
+
+	err = h.tmpl.ExecuteTemplate(w, "hand_rank", ui)
+	if err != nil {
+		http.Error(w, "Template error: "+err.Error(), http.StatusInternalServerError)
+	}
+}
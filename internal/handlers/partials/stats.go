@@ -8,10 +8,14 @@ import (
 
 func (h *Handler) TrackStats(w http.ResponseWriter, r *http.Request) {
 	fmatrixParam := r.URL.Query().Get("fmatrix")
-	match := GetMatch(r)
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+	view := GetViewFilters(r, match)
 
 	// Recalculate stats dynamically based on the current matrix mode
-	stats, err := truco.ComputePairStats(fmatrixParam == "true", match.GetStatsFilter())
+	stats, err := truco.ComputePairStats(fmatrixParam == "true", view.Theirs)
 	if err != nil {
 		http.Error(w, "Failed to compute stats: "+err.Error(), http.StatusInternalServerError)
 		return
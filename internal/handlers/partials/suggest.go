@@ -0,0 +1,76 @@
+package partials
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+	"truco/pkg/truco"
+)
+
+// Rival hands sampled to suggest a card: by default, and at most
+const (
+	SUGGEST_SAMPLES     = 500
+	MAX_SUGGEST_SAMPLES = 5000
+)
+
+// Suggests the card to play for the current player of the tracked match, in its rules.
+//
+// Query params:
+//   - state: encoded match
+//   - hand: full hand of the current player, eg. "1e 7o 3c".
+//     Defaults to the private hand, if the current player is the user
+//   - samples: optional, rival hands to sample (default SUGGEST_SAMPLES, up to MAX_SUGGEST_SAMPLES)
+//   - seed: optional, to get the same suggestion twice
+func (h *Handler) SuggestCard(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
+
+	mHand := truco.NewHand(query.Get("hand"))
+	view := match.GetPlayView()
+	if len(mHand) == 0 && match.Private != nil && match.Private.Seat == match.CPlayer {
+		mHand, view, _ = match.GetPrivatePlayView()
+	}
+	if len(mHand) != 3 {
+		http.Error(w, "Hand must have exactly 3 cards", http.StatusBadRequest)
+		return
+	}
+
+	samples, err := strconv.Atoi(query.Get("samples"))
+	if err != nil {
+		samples = SUGGEST_SAMPLES
+	}
+	samples = min(max(samples, 1), MAX_SUGGEST_SAMPLES)
+	seed, err := strconv.ParseUint(query.Get("seed"), 10, 64)
+	if err != nil {
+		seed = uint64(time.Now().UnixNano())
+	}
+
+	suggestion, err := truco.SuggestCardRules(match.Rules, mHand, view, samples, seed)
+	if err != nil {
+		http.Error(w, "Can't suggest a card: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	wins := make(map[string]float32, len(suggestion.Cards))
+	for i, c := range suggestion.Cards {
+		wins[c.ToString()] = suggestion.Wins[i]
+	}
+	data := struct {
+		Card    string             `json:"card"`
+		Wins    map[string]float32 `json:"wins"`
+		Samples int                `json:"samples"`
+	}{
+		Card:    suggestion.Card.ToString(),
+		Wins:    wins,
+		Samples: suggestion.Samples,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		http.Error(w, "Failed to marshal suggestion: "+err.Error(), http.StatusInternalServerError)
+	}
+}
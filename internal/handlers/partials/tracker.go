@@ -9,7 +9,10 @@ import (
 
 func (h *Handler) TrackAct(w http.ResponseWriter, r *http.Request) {
 	actionParam := r.URL.Query().Get("action")
-	match := GetMatch(r)
+	match, ok := GetMatchOrError(w, r)
+	if !ok {
+		return
+	}
 
 	action := fsm.ValidAction(actionParam)
 	tmplName, data := processActionFSM(action, match, r)
@@ -23,12 +26,7 @@ func (h *Handler) TrackAct(w http.ResponseWriter, r *http.Request) {
 func processActionFSM(action fsm.ValidAction, match *fsm.Match, r *http.Request) (string, any) {
 	var doneActions []fsm.ValidAction
 
-	switch action {
-	case fsm.PLAY:
-		card := r.URL.Query().Get("card")
-		_ = match.Play(truco.NewCard(card))
-
-	case fsm.ASK_T, fsm.ASK_RT, fsm.ASK_V4:
+	if match.IsTrucoRaise(action) {
 		_ = match.Ask(fsm.RequestTruco)
 		return "truco_modal", struct {
 			Player uint8
@@ -39,8 +37,18 @@ func processActionFSM(action fsm.ValidAction, match *fsm.Match, r *http.Request)
 			Action: action,
 			State:  string(match.Encode()),
 		}
+	}
 
-	case fsm.ASK_E, fsm.ASK_RE, fsm.ASK_FE:
+	switch action {
+	case fsm.PLAY:
+		card := r.URL.Query().Get("card")
+		_ = match.Play(truco.NewCard(card))
+
+	case fsm.SHOW:
+		card := r.URL.Query().Get("card")
+		_ = match.Show(truco.NewCard(card))
+
+	case fsm.ASK_E, fsm.ASK_RE, fsm.ASK_FE, fsm.FLOR, fsm.CONTRAFLOR, fsm.CONTRAFLOR_R:
 		return "envido_selector", struct {
 			Envidos [][]fsm.ValidAction
 			Players int
@@ -52,29 +60,26 @@ func processActionFSM(action fsm.ValidAction, match *fsm.Match, r *http.Request)
 		}
 
 	case fsm.ACCEPT:
-		prevTruco := match.CTruco
-		_ = match.Accept()
-		if match.CTruco > prevTruco {
-			switch match.CTruco {
-			case 2:
-				doneActions = append(doneActions, fsm.ASK_T)
-			case 3:
-				doneActions = append(doneActions, fsm.ASK_RT)
-			case 4:
-				doneActions = append(doneActions, fsm.ASK_V4)
+		unsubscribe := match.Subscribe(fsm.ObserverFunc(func(e fsm.Event) {
+			if bet, ok := e.(fsm.BetAccepted); ok && !bet.IsEnvido {
+				doneActions = append(doneActions, match.TrucoRaise(bet.Bet))
 			}
-		}
+		}))
+		_ = match.Accept()
+		unsubscribe()
 
 	case fsm.FOLD, fsm.FOLD_NQ, fsm.FOLD_SB:
 		match.Fold()
 
 	case fsm.ANNOUN:
 		// Envido Betting Sequence
+		done := fsm.ASK_E
 		idxStr := r.URL.Query().Get("combination_idx")
 		if idx, err := strconv.Atoi(idxStr); err == nil {
 			combos := match.ValidEnvidos()
 			if idx >= 0 && idx < len(combos) {
 				combo := combos[idx]
+				done = combo[0]
 				for _, act := range combo {
 					req := fsm.RequestEnvido
 					switch act {
@@ -82,6 +87,12 @@ func processActionFSM(action fsm.ValidAction, match *fsm.Match, r *http.Request)
 						req = fsm.RequestReal
 					case fsm.ASK_FE:
 						req = fsm.RequestFalta
+					case fsm.FLOR:
+						req = fsm.RequestFlor
+					case fsm.CONTRAFLOR:
+						req = fsm.RequestContraflor
+					case fsm.CONTRAFLOR_R:
+						req = fsm.RequestContraflorResto
 					}
 					_ = match.Ask(req)
 				}
@@ -90,7 +101,7 @@ func processActionFSM(action fsm.ValidAction, match *fsm.Match, r *http.Request)
 		_ = match.Accept()
 
 		// Announcements: loop while someone needs to announce
-		for {
+		for match.CState == match.Announcing {
 			p := match.CPlayerE()
 			if p == 255 {
 				break
@@ -102,14 +113,16 @@ func processActionFSM(action fsm.ValidAction, match *fsm.Match, r *http.Request)
 			}
 			_ = match.Announce(uint8(score))
 		}
-		doneActions = append(doneActions, fsm.ASK_E)
+		doneActions = append(doneActions, done)
 	}
 
 	// default: next action tracker (next player's turn)
 	return "tracker", TrackerData{
 		ActionTitle: "Jugador " + string(rune('1'+match.CPlayer)),
 		Actions:     match.ValidActions(),
+		Raises:      TrucoRaises(match),
 		DoneActions: doneActions,
 		State:       string(match.Encode()),
+		Liars:       GetLiars(match),
 	}
 }
@@ -25,6 +25,7 @@ func (s *Server) routes() {
 	// Initialize Handlers
 	matrixHandler := pages.NewMatrixHandler(s.Tmpl)
 	homeHandler := pages.NewHomeHandler(s.Tmpl)
+	exploreHandler := pages.NewExploreHandler(s.Tmpl)
 	handler := partials.NewHandler(s.Tmpl)
 
 	// Serve static files
@@ -40,4 +41,14 @@ func (s *Server) routes() {
 	s.HandleFunc("/get-lower-cards", handler.GetLowerCards)
 	s.HandleFunc("/track-act", handler.TrackAct)
 	s.HandleFunc("/track-stats", handler.TrackStats)
+	s.HandleFunc("/track-envido", handler.TrackEnvido)
+	s.HandleFunc("/track-rank", handler.TrackRank)
+	s.HandleFunc("/track-locations", handler.TrackLocations)
+	s.HandleFunc("/track-hand", handler.TrackHand)
+	s.HandleFunc("/track-my-stats", handler.TrackMyStats)
+	s.HandleFunc("/suggest-card", handler.SuggestCard)
+	s.HandleFunc("/range", handler.Range)
+
+	// Hand explorer
+	s.Handle("/explore", exploreHandler)
 }
@@ -31,13 +31,12 @@ func (a *AnnouncingState) fold() {
 
 	} else {
 		highestE, _ := a.match.winnerE()
-		a.match.Envidos[a.match.CPlayerE()] = highestE + 100
+		player := uint8(a.match.CPlayerE())
+		a.match.Envidos[player] = truco.EnvidoAtMost(highestE)
+		a.match.emit(EnvidoAnnounced{Player: player, Envido: a.match.Envidos[player]})
 	}
 
-	if a.match.isEnvidoFull() {
-		a.match.CState = a.match.Playing
-		a.match.IsEnvido = false
-	}
+	a.closeIfFull()
 }
 
 func (a *AnnouncingState) announce(score uint8) error {
@@ -48,10 +47,14 @@ func (a *AnnouncingState) announce(score uint8) error {
 		return nil
 	}
 
-	if score <= 7 || (score >= 20 && score <= truco.MAX_ENVIDO_AR) {
+	// with rules that score flor apart, a flor is announced as its value (see truco.Ruleset.Envido)
+	isFlorValue := a.match.Flor != 0 && a.match.Rules.HasFlor() && score >= 200
+	if score <= 7 || (score >= 20 && score <= a.match.Rules.MaxEnvido()) || isFlorValue {
 		highestE, _ := a.match.winnerE()
-		if highestE < score {
-			a.match.Envidos[a.match.CPlayerE()] = score
+		if highestE < score || !a.match.House.ForcedSonBuenas {
+			player := uint8(a.match.CPlayerE())
+			a.match.Envidos[player] = truco.EnvidoExact(score)
+			a.match.emit(EnvidoAnnounced{Player: player, Envido: a.match.Envidos[player]})
 		} else {
 			// player announced loosing envido (lower than highest)
 			a.match.Fold()
@@ -61,12 +64,20 @@ func (a *AnnouncingState) announce(score uint8) error {
 		return fmt.Errorf("Score must be a valid envido")
 	}
 
-	if a.match.isEnvidoFull() {
-		a.match.CState = a.match.Playing
-		a.match.IsEnvido = false
+	a.closeIfFull()
+	return nil
+}
+
+// Goes back to playing once every player announced their envido
+func (a *AnnouncingState) closeIfFull() {
+	if a.match.CState != a.match.Announcing || !a.match.isEnvidoFull() {
+		return
 	}
+	a.match.CState = a.match.Playing
+	a.match.IsEnvido = false
 
-	return nil
+	highest, winner := a.match.winnerE()
+	a.match.emit(EnvidoResolved{Winner: winner, Score: highest, Points: a.match.envidoWon(winner)})
 }
 
 func (a *AnnouncingState) stateId() uint8 {
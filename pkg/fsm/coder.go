@@ -1,40 +1,170 @@
 package fsm
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"slices"
+	"truco/pkg/truco"
 )
 
 // Encodes and Decodes a Match to a byte array:
-// a state that can resume a single match
+// a state that can resume a single match.
+//
+// A state is base64 (url safe, no padding) of:
+//   - version: schema of the payload, see STATE_VERSION
+//   - flags: STATE_SIGNED
+//   - payload: the match, in binary
+//   - signature: HMAC of everything before it, if signed
+//
+// States of older versions are migrated to the current Match when decoded.
+// Version 1 is the old JSON encoding: base64 of the JSON of the Match (see decodeV1).
+
+const (
+	STATE_VERSION  = 2  // version of the states Encode writes
+	STATE_SIGNED   = 1  // flag: the state ends with a signature
+	SIGNATURE_SIZE = 16 // bytes of the HMAC kept in the state
+)
+
+// Key to sign states, nil to leave them unsigned
+var stateKey []byte
+
+// Sets the key states are signed with.
+// Once set, Decode rejects states without a valid signature: links can't be edited.
+func SetStateKey(key []byte) {
+	stateKey = key
+}
+
+// Decoders of the payload of every version, migrating it to the current Match
+var decoders = map[byte]func(payload []byte) (*Match, error){
+	2: decodeV2,
+}
+
+var stateEncoding = base64.RawURLEncoding
 
 // Encodes a Match to a byte array that the frontend can save
 func (m *Match) Encode() []byte {
 	m.CStateId = m.CState.stateId()
-	data, err := json.Marshal(m)
-	if err != nil {
-		return []byte{}
+
+	data := []byte{STATE_VERSION, 0}
+	if stateKey != nil {
+		data[1] |= STATE_SIGNED
+	}
+	data = append(data, m.encodeV2()...)
+	if stateKey != nil {
+		data = append(data, sign(data)...)
 	}
-	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(data)))
-	base64.StdEncoding.Encode(encoded, data)
+
+	encoded := make([]byte, stateEncoding.EncodedLen(len(data)))
+	stateEncoding.Encode(encoded, data)
 	return encoded
 }
 
-// Decodes a byte array match from the frontend
-func Decode(encoded []byte) *Match {
-	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
-	n, err := base64.StdEncoding.Decode(decoded, encoded)
-	if err != nil {
-		return NewMatch()
+// Decodes a byte array match from the frontend.
+//
+// Returns an error if the state is not valid, was edited,
+// or is not signed while a key is set (see SetStateKey)
+func Decode(encoded []byte) (*Match, error) {
+	if bytes.HasPrefix(encoded, []byte("eyJ")) {
+		// base64 of '{"': version 1
+		if stateKey != nil {
+			return nil, fmt.Errorf("State is not signed")
+		}
+		return decodeV1(encoded)
 	}
 
-	m := &Match{}
-	err = json.Unmarshal(decoded[:n], m)
+	data := make([]byte, stateEncoding.DecodedLen(len(encoded)))
+	n, err := stateEncoding.Decode(data, encoded)
 	if err != nil {
-		return NewMatch()
+		return nil, fmt.Errorf("State is not valid base64: %w", err)
+	}
+	data = data[:n]
+	if len(data) < 2 {
+		return nil, fmt.Errorf("State is too short")
+	}
+
+	version, flags := data[0], data[1]
+	payload := data[2:]
+	if flags&STATE_SIGNED != 0 {
+		if len(payload) < SIGNATURE_SIZE {
+			return nil, fmt.Errorf("State is too short")
+		}
+		body, signature := data[:len(data)-SIGNATURE_SIZE], data[len(data)-SIGNATURE_SIZE:]
+		if stateKey != nil && !hmac.Equal(sign(body), signature) {
+			return nil, fmt.Errorf("State signature is not valid")
+		}
+		payload = body[2:]
+	} else if stateKey != nil {
+		return nil, fmt.Errorf("State is not signed")
 	}
 
+	decoder, ok := decoders[version]
+	if !ok {
+		return nil, fmt.Errorf("State version %d is not supported", version)
+	}
+	m, err := decoder(payload)
+	if err != nil {
+		return nil, err
+	}
 	m.bindStates()
+	m.setCState()
+	return m, nil
+}
+
+// Returns an error if a field of a decoded match is out of range,
+// so a state can't make the match index past its players or cards
+func (m *Match) validate() error {
+	if len(m.Cards) != NUM_PLAYERS || len(m.Envidos) != NUM_PLAYERS || len(m.Shown) != NUM_PLAYERS {
+		return fmt.Errorf("State is not valid: expected %d players", NUM_PLAYERS)
+	}
+	if m.CPlayer >= NUM_PLAYERS {
+		return fmt.Errorf("State is not valid: player %d doesn't exist", m.CPlayer+1)
+	}
+	for _, player := range []uint8{m.CTrucoAsk, m.CEnvidoAsk, m.WinnerT} {
+		if player >= NUM_PLAYERS && player != 255 {
+			return fmt.Errorf("State is not valid: player %d doesn't exist", player+1)
+		}
+	}
+	if (m.CEnvido != 0 || m.Flor != 0) && m.CEnvidoAsk == 255 {
+		return fmt.Errorf("State is not valid: envido bet without a player that asked for it")
+	}
+	if m.Flor >= 1<<NUM_PLAYERS {
+		return fmt.Errorf("State is not valid: flor of a player that doesn't exist")
+	}
+	if m.CTruco < 1 || int(m.CTruco) > len(m.Rules.Bets().Truco) {
+		return fmt.Errorf("State is not valid: truco bet %d doesn't exist in %s", m.CTruco, m.Rules.Name())
+	}
+	for player := range m.Cards {
+		if len(m.Cards[player]) != 3 || len(m.Shown[player]) > 3 {
+			return fmt.Errorf("State is not valid: player %d has more than 3 cards", player+1)
+		}
+		if m.Envidos[player].Kind > truco.ENVIDO_DECLINED {
+			return fmt.Errorf("State is not valid: unknown envido of player %d", player+1)
+		}
+	}
+	if m.Private != nil {
+		if m.Private.Seat >= NUM_PLAYERS {
+			return fmt.Errorf("State is not valid: seat %d doesn't exist", m.Private.Seat+1)
+		}
+		if len(m.Private.Hand) > 3 || len(m.Private.Partner) > 3 {
+			return fmt.Errorf("State is not valid: a private hand has more than 3 cards")
+		}
+	}
+	return nil
+}
+
+// HMAC of data, with the key of the states
+func sign(data []byte) []byte {
+	mac := hmac.New(sha256.New, stateKey)
+	mac.Write(data)
+	return mac.Sum(nil)[:SIGNATURE_SIZE]
+}
+
+// Sets the current state from CStateId
+func (m *Match) setCState() {
 	switch m.CStateId {
 	case 1:
 		m.CState = m.Playing
@@ -47,6 +177,264 @@ func Decode(encoded []byte) *Match {
 	default:
 		m.CState = m.Playing
 	}
+}
+
+// Version 1: base64 of the JSON of the Match, as it was before the binary versions.
+// Envidos were the old uint8 codes (see truco.EnvidoFromCode):
+// encoding/json wrote them as a single base64 string.
+// There were no rules nor house rules: argentinian truco, with DEFAULT_HOUSE_RULES.
+func decodeV1(encoded []byte) (*Match, error) {
+	decoded := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	n, err := base64.StdEncoding.Decode(decoded, encoded)
+	if err != nil {
+		return nil, fmt.Errorf("State is not valid base64: %w", err)
+	}
 
-	return m
+	var legacy struct {
+		Cards      [][]truco.Card `json:"cards"`
+		CTruco     uint8          `json:"c_truco"`
+		CTrucoAsk  uint8          `json:"c_truco_ask"`
+		CPlayer    uint8          `json:"c_player"`
+		Envidos    []byte         `json:"envidos"`
+		CEnvido    uint8          `json:"c_envido"`
+		CEnvidoNo  uint8          `json:"c_envido_no"`
+		CEnvidoAsk uint8          `json:"c_envido_ask"`
+		IsEnvido   bool           `json:"is_envido"`
+		WinnerT    uint8          `json:"winner_t"`
+		CStateId   uint8          `json:"c_state_id"`
+	}
+	if err := json.Unmarshal(decoded[:n], &legacy); err != nil {
+		return nil, fmt.Errorf("State is not valid: %w", err)
+	}
+
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	m.Cards = legacy.Cards
+	m.CTruco, m.CTrucoAsk, m.CPlayer = legacy.CTruco, legacy.CTrucoAsk, legacy.CPlayer
+	m.CEnvido, m.CEnvidoNo, m.CEnvidoAsk = legacy.CEnvido, legacy.CEnvidoNo, legacy.CEnvidoAsk
+	m.IsEnvido, m.WinnerT, m.CStateId = legacy.IsEnvido, legacy.WinnerT, legacy.CStateId
+	m.Envidos = make([]truco.EnvidoConstraint, len(legacy.Envidos))
+	for player, code := range legacy.Envidos {
+		m.Envidos[player] = truco.EnvidoFromCode(code)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+
+	m.setCState()
+	return m, nil
+}
+
+// Bits of the flags of the payload of version 2
+const (
+	v2IsEnvido = 1 << iota
+	v2Private
+	v2Eleven
+)
+
+// Bits of the house rules of the payload of version 2
+const (
+	v2Flor = 1 << iota
+	v2FlorMandatory
+	v2ContraflorAlResto
+	v2EnvidoFirstRound
+	v2LeyDeLaFalta
+	v2ForcedSonBuenas
+	v2LiePenalty
+)
+
+// Version 2, as bytes:
+//   - cards: NUM_PLAYERS*3 cards
+//   - CTruco, CTrucoAsk, CPlayer, CEnvido, CEnvidoNo, CEnvidoAsk, WinnerT, CStateId
+//   - flags: v2IsEnvido, v2Private, v2Eleven
+//   - rules: length and name of the ruleset (see truco.NewRuleset), and its muestra
+//   - house: flags of the house rules (v2Flor...), Points, Scores of both teams, and Flor
+//   - envidos: kind, value, amount of values and values, for each player
+//   - shown: amount of cards and cards, for each player
+//   - private, if v2Private: seat, amount of cards and cards of the hand, and of the partner
+func (m *Match) encodeV2() []byte {
+	data := make([]byte, 0, 64)
+	for _, cards := range m.Cards {
+		for _, c := range cards {
+			data = append(data, encodeCard(c))
+		}
+	}
+	data = append(data, m.CTruco, m.CTrucoAsk, m.CPlayer, m.CEnvido, m.CEnvidoNo, m.CEnvidoAsk, m.WinnerT, m.CStateId)
+
+	var flags byte
+	if m.IsEnvido {
+		flags |= v2IsEnvido
+	}
+	if m.Private != nil {
+		flags |= v2Private
+	}
+	if m.Eleven {
+		flags |= v2Eleven
+	}
+	name := m.Rules.Name()
+	data = append(data, flags, byte(len(name)))
+	data = append(data, name...)
+	data = append(data, encodeCard(m.Rules.Muestra()))
+	data = append(data, m.House.flags(), m.House.Points, m.Scores[0], m.Scores[1], m.Flor)
+
+	for _, e := range m.Envidos {
+		data = append(data, byte(e.Kind), e.Value, byte(len(e.Values)))
+		data = append(data, e.Values...)
+	}
+	for player := range m.Cards {
+		data = appendCards(data, m.shown(uint8(player)))
+	}
+	if m.Private != nil {
+		data = append(data, m.Private.Seat)
+		data = appendCards(data, m.Private.Hand)
+		data = appendCards(data, m.Private.Partner)
+	}
+	return data
+}
+
+func decodeV2(payload []byte) (*Match, error) {
+	r := &stateReader{data: payload}
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+
+	for _, cards := range m.Cards {
+		for t := range cards {
+			cards[t] = r.card()
+		}
+	}
+	m.CTruco, m.CTrucoAsk, m.CPlayer = r.byte(), r.byte(), r.byte()
+	m.CEnvido, m.CEnvidoNo, m.CEnvidoAsk = r.byte(), r.byte(), r.byte()
+	m.WinnerT, m.CStateId = r.byte(), r.byte()
+
+	flags := r.byte()
+	m.IsEnvido = flags&v2IsEnvido != 0
+	m.Eleven = flags&v2Eleven != 0
+	name := string(r.bytes(int(r.byte())))
+	rules, err := truco.NewRuleset(name, r.card())
+	if err != nil && r.err == nil {
+		r.err = fmt.Errorf("State is not valid: %w", err)
+	}
+	m.Rules = rules
+	m.House = houseFromFlags(r.byte())
+	m.House.Points = r.byte()
+	m.Scores = [2]uint8{r.byte(), r.byte()}
+	m.Flor = r.byte()
+	if err := m.House.Validate(); err != nil && r.err == nil {
+		r.err = fmt.Errorf("State is not valid: %w", err)
+	}
+
+	for player := range m.Envidos {
+		m.Envidos[player] = truco.EnvidoConstraint{Kind: truco.EnvidoKind(r.byte()), Value: r.byte()}
+		if n := int(r.byte()); n > 0 {
+			m.Envidos[player].Values = r.bytes(n)
+		}
+	}
+	for player := range m.Shown {
+		m.Shown[player] = r.cards()
+	}
+	if flags&v2Private != 0 {
+		m.Private = &PrivateHands{Seat: r.byte(), Hand: r.cards(), Partner: r.cards()}
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.data) != 0 {
+		return nil, fmt.Errorf("State has %d unexpected bytes", len(r.data))
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// House rules as the bits v2Flor...
+func (h HouseRules) flags() byte {
+	var flags byte
+	for bit, on := range []bool{h.Flor, h.FlorMandatory, h.ContraflorAlResto, h.EnvidoFirstRound, h.LeyDeLaFalta, h.ForcedSonBuenas, h.LiePenalty} {
+		if on {
+			flags |= 1 << bit
+		}
+	}
+	return flags
+}
+
+// House rules of the bits v2Flor..., without Points
+func houseFromFlags(flags byte) HouseRules {
+	return HouseRules{
+		Flor:              flags&v2Flor != 0,
+		FlorMandatory:     flags&v2FlorMandatory != 0,
+		ContraflorAlResto: flags&v2ContraflorAlResto != 0,
+		EnvidoFirstRound:  flags&v2EnvidoFirstRound != 0,
+		LeyDeLaFalta:      flags&v2LeyDeLaFalta != 0,
+		ForcedSonBuenas:   flags&v2ForcedSonBuenas != 0,
+		LiePenalty:        flags&v2LiePenalty != 0,
+	}
+}
+
+// Suits of the cards, by their index in a byte
+const stateSuits = "eboc"
+
+// A card in a byte: number and index of the suit. truco.NO_CARD is 0
+func encodeCard(c truco.Card) byte {
+	if c == truco.NO_CARD {
+		return 0
+	}
+	return c.N<<2 | byte(bytes.IndexByte([]byte(stateSuits), c.S))
+}
+
+func appendCards(data []byte, cards []truco.Card) []byte {
+	data = append(data, byte(len(cards)))
+	for _, c := range cards {
+		data = append(data, encodeCard(c))
+	}
+	return data
+}
+
+// Reads a payload byte by byte, keeping the first error
+type stateReader struct {
+	data []byte
+	err  error
+}
+
+func (r *stateReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if len(r.data) < n {
+		r.err = fmt.Errorf("State is too short")
+		return nil
+	}
+	b := bytes.Clone(r.data[:n])
+	r.data = r.data[n:]
+	return b
+}
+
+func (r *stateReader) byte() byte {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *stateReader) card() truco.Card {
+	b := r.byte()
+	if b == 0 {
+		return truco.NO_CARD
+	}
+	c := truco.Card{N: b >> 2, S: stateSuits[b&3]}
+	if !slices.Contains(truco.ALL_CARDS, c) && r.err == nil {
+		r.err = fmt.Errorf("State has a card that doesn't exist")
+	}
+	return c
+}
+
+func (r *stateReader) cards() []truco.Card {
+	n := int(r.byte())
+	if n == 0 {
+		return nil
+	}
+	cards := make([]truco.Card, n)
+	for i := range cards {
+		cards[i] = r.card()
+	}
+	return cards
 }
@@ -0,0 +1,197 @@
+package fsm
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"truco/pkg/truco"
+)
+
+// Returns a match with every field of the state set
+func codedMatch(t *testing.T) *Match {
+	m := NewMatchRules(truco.UruguayanRules{M: truco.Card{N: 4, S: 'c'}}, DEFAULT_HOUSE_RULES)
+	hand := []truco.Card{{N: 1, S: 'e'}, {N: 7, S: 'o'}, {N: 3, S: 'b'}}
+	if err := m.SetPrivateHands(1, hand, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range []truco.Card{{N: 4, S: 'e'}, {N: 7, S: 'o'}} {
+		if err := m.Play(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	m.Envidos[0] = truco.EnvidoOneOf(20, 21)
+	m.Envidos[1] = truco.EnvidoExact(27)
+	m.House.LiePenalty = true
+	m.Shown[2] = []truco.Card{{N: 12, S: 'c'}}
+	_ = m.Ask(RequestTruco)
+	return m
+}
+
+func TestEncodeDecode(t *testing.T) {
+	m := codedMatch(t)
+	encoded := m.Encode()
+	if len(encoded) > 80 {
+		t.Errorf("expected a short state, got %d bytes", len(encoded))
+	}
+
+	d, err := Decode(encoded)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want, _ := json.Marshal(m)
+	got, _ := json.Marshal(d)
+	if string(want) != string(got) {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+	if d.Rules != m.Rules {
+		t.Errorf("expected rules %v, got %v", m.Rules, d.Rules)
+	}
+	if d.CState != d.Responding {
+		t.Errorf("expected the responding state")
+	}
+	if string(d.Encode()) != string(encoded) {
+		t.Errorf("expected the same state encoded twice")
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	encoded := codedMatch(t).Encode()
+
+	for name, state := range map[string]string{
+		"empty":      "",
+		"not base64": "%%%",
+		"truncated":  string(encoded[:len(encoded)-4]),
+		"version":    base64.RawURLEncoding.EncodeToString([]byte{9, 0, 1, 2}),
+		"card":       base64.RawURLEncoding.EncodeToString(append([]byte{STATE_VERSION, 0, 255}, make([]byte, 40)...)),
+	} {
+		if _, err := Decode([]byte(state)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// Fields out of range decode with an error, not a match that panics later
+func TestDecodeOutOfRange(t *testing.T) {
+	for name, edit := range map[string]func(m *Match){
+		"player":        func(m *Match) { m.CPlayer = 7 },
+		"truco asker":   func(m *Match) { m.CTrucoAsk = 4 },
+		"envido asker":  func(m *Match) { m.CEnvidoAsk = 200 },
+		"truco winner":  func(m *Match) { m.WinnerT = 4 },
+		"truco bet":     func(m *Match) { m.CTruco = 5 },
+		"no truco bet":  func(m *Match) { m.CTruco = 0 },
+		"envido kind":   func(m *Match) { m.Envidos[2].Kind = 42 },
+		"private seat":  func(m *Match) { m.Private.Seat = 4 },
+		"private hand":  func(m *Match) { m.Private.Hand = append(m.Private.Hand, truco.Card{N: 2, S: 'c'}) },
+		"shown cards":   func(m *Match) { m.Shown[0] = truco.NewHand("1e 2e 3e 4e") },
+		"legacy player": nil,
+	} {
+		m := codedMatch(t)
+		var state []byte
+		if edit == nil {
+			data, _ := base64.StdEncoding.DecodeString(LEGACY_STATE)
+			var fields map[string]any
+			_ = json.Unmarshal(data, &fields)
+			fields["c_player"] = 9
+			data, _ = json.Marshal(fields)
+			state = []byte(base64.StdEncoding.EncodeToString(data))
+		} else {
+			edit(m)
+			state = m.Encode()
+		}
+		if _, err := Decode(state); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+
+	if _, err := Decode(codedMatch(t).Encode()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+// State of version 1, as encoded before the binary versions:
+// 1e and 4c played, player 1 announced 27 and the envido asked by player 3 is being announced
+const LEGACY_STATE = "eyJjYXJkcyI6W1t7Ik4iOjEsIlMiOjEwMX0seyJOIjowLCJTIjowfSx7Ik4iOjAsIlMiOjB9XSxbeyJOIjo0LCJTIjo5OX0seyJOIjowLCJTIjowfSx7Ik4iOjAsIlMiOjB9XSxbeyJOIjowLCJTIjowfSx7Ik4iOjAsIlMiOjB9LHsiTiI6MCwiUyI6MH1dLFt7Ik4iOjAsIlMiOjB9LHsiTiI6MCwiUyI6MH0seyJOIjowLCJTIjowfV1dLCJjX3RydWNvIjoxLCJjX3RydWNvX2FzayI6MjU1LCJjX3BsYXllciI6MiwiZW52aWRvcyI6IkcvLy8vdz09IiwiY19lbnZpZG8iOjIsImNfZW52aWRvX25vIjoxLCJjX2Vudmlkb19hc2siOjIsImlzX2VudmlkbyI6dHJ1ZSwid2lubmVyX3QiOjI1NSwiY19zdGF0ZV9pZCI6Mn0="
+
+func TestDecodeLegacy(t *testing.T) {
+	d, err := Decode([]byte(LEGACY_STATE))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.Cards[0][0] != (truco.Card{N: 1, S: 'e'}) || d.Cards[1][0] != (truco.Card{N: 4, S: 'c'}) || d.CPlayer != 2 {
+		t.Errorf("legacy cards not migrated: %v, player %d", d.Cards, d.CPlayer)
+	}
+	if d.Envidos[0].String() != "27" || !d.Envidos[1].IsAny() {
+		t.Errorf("legacy envidos not migrated: %v", d.Envidos)
+	}
+	if !d.IsEnvido || d.CEnvido != 2 || d.CEnvidoAsk != 2 || d.CState != d.Announcing {
+		t.Errorf("legacy envido bet not migrated: %+v", d)
+	}
+	if d.Rules != (truco.ArgentineRules{}) || d.House != DEFAULT_HOUSE_RULES || len(d.Shown) != NUM_PLAYERS {
+		t.Errorf("expected argentinian rules with the default house rules, got %v and %+v", d.Rules, d.House)
+	}
+}
+
+func TestEncodeHouse(t *testing.T) {
+	m := NewMatch(HouseRules{Flor: true, ContraflorAlResto: true, LeyDeLaFalta: true, Points: 15})
+	_ = m.SetScores(3, 12)
+	_ = m.Ask(RequestFlor)
+
+	d, err := Decode(m.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d.House != m.House || d.Scores != m.Scores || d.Flor != 1 || d.CState != d.Responding {
+		t.Errorf("expected %+v at %v, got %+v at %v", m.House, m.Scores, d.House, d.Scores)
+	}
+
+	// a game to 20 points can't be played
+	m.House.Points = 20
+	if _, err := Decode(m.Encode()); err == nil {
+		t.Errorf("expected error with invalid house rules")
+	}
+}
+
+func TestSignedState(t *testing.T) {
+	unsigned := codedMatch(t).Encode()
+
+	SetStateKey([]byte("secret"))
+	defer SetStateKey(nil)
+
+	signed := codedMatch(t).Encode()
+	if _, err := Decode(signed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := Decode(unsigned); err == nil {
+		t.Errorf("expected error decoding an unsigned state")
+	}
+
+	// edit the truco bet
+	data, _ := base64.RawURLEncoding.DecodeString(string(signed))
+	data[2+NUM_PLAYERS*3]++
+	if _, err := Decode([]byte(base64.RawURLEncoding.EncodeToString(data))); err == nil {
+		t.Errorf("expected error decoding an edited state")
+	}
+
+	SetStateKey([]byte("another secret"))
+	if _, err := Decode(signed); err == nil {
+		t.Errorf("expected error decoding a state signed with another key")
+	}
+}
+
+// Any state that decodes can be played from: go test -fuzz FuzzDecode ./pkg/fsm
+func FuzzDecode(f *testing.F) {
+	f.Add(codedMatch(&testing.T{}).Encode())
+	f.Add(NewMatch(DEFAULT_HOUSE_RULES).Encode())
+	f.Fuzz(func(t *testing.T, state []byte) {
+		m, err := Decode(state)
+		if err != nil {
+			return
+		}
+		m.ValidActions()
+		m.ValidEnvidos()
+		m.GetPlayView()
+		m.GetPrivatePlayView()
+		m.GetScore()
+		_ = m.Play(truco.Card{N: 1, S: 'e'})
+	})
+}
@@ -31,6 +31,10 @@ func (e *EndState) stateId() uint8 {
 	return 0
 }
 
+// The envido winner may show their cards
 func (e *EndState) validActions() []ValidAction {
+	if e.match.canShow() {
+		return []ValidAction{SHOW}
+	}
 	return []ValidAction{}
 }
@@ -0,0 +1,169 @@
+package fsm
+
+import "truco/pkg/truco"
+
+// Events of a match, for whoever needs to react to the game
+// (the tracker, loggers, analytics) without diffing states.
+//
+// Subscribe an Observer to a match to receive its events, in the order they happen.
+// Observers are not part of the state: a decoded match has none.
+
+// Something that happened in a match
+type Event interface {
+	Name() string // snake_case name of the event, eg. for logs
+}
+
+// A player played a card
+type CardPlayed struct {
+	Player uint8
+	Card   truco.Card
+	Turn   uint8 // round of the hand (0-2)
+}
+
+// Every player played their card of a round
+type TrickWon struct {
+	Turn   uint8
+	Player uint8 // player with the highest card, 255 if tied between teams ('parda')
+}
+
+// A player asked for truco, retruco or vale 4
+type TrucoRaised struct {
+	Player uint8
+	Bet    uint8 // bet if accepted (2-4)
+}
+
+// A player asked for envido, or raised it
+type EnvidoRaised struct {
+	Player uint8
+	Bet    uint8 // points if accepted, 255 for falta envido
+}
+
+// A bet was accepted ('quiero')
+type BetAccepted struct {
+	Asker    uint8 // player that asked for the bet
+	IsEnvido bool
+	Bet      uint8 // points at stake
+}
+
+// A bet was declined ('no quiero')
+type BetDeclined struct {
+	Asker    uint8 // player that asked for the bet, who wins the points
+	IsEnvido bool
+	Points   uint8 // points won by the asker
+}
+
+// A player announced their envido, or said 'son buenas'
+type EnvidoAnnounced struct {
+	Player uint8
+	Envido truco.EnvidoConstraint
+}
+
+// The envido is over
+type EnvidoResolved struct {
+	Winner uint8
+	Score  uint8 // envido of the winner, 0 if the envido was declined
+	Points uint8
+}
+
+// The hand is over
+type MatchEnded struct {
+	Winner uint8 // a player of the team that won the truco
+	Points uint8
+}
+
+func (CardPlayed) Name() string      { return "card_played" }
+func (TrickWon) Name() string        { return "trick_won" }
+func (TrucoRaised) Name() string     { return "truco_raised" }
+func (EnvidoRaised) Name() string    { return "envido_raised" }
+func (BetAccepted) Name() string     { return "bet_accepted" }
+func (BetDeclined) Name() string     { return "bet_declined" }
+func (EnvidoAnnounced) Name() string { return "envido_announced" }
+func (EnvidoResolved) Name() string  { return "envido_resolved" }
+func (MatchEnded) Name() string      { return "match_ended" }
+
+// Receives the events of a match
+type Observer interface {
+	OnEvent(e Event)
+}
+
+// A func as an Observer
+type ObserverFunc func(e Event)
+
+func (f ObserverFunc) OnEvent(e Event) {
+	f(e)
+}
+
+type subscription struct {
+	id int
+	o  Observer
+}
+
+// Subscribes o to the events of the match.
+// Returns a func that unsubscribes it.
+func (m *Match) Subscribe(o Observer) func() {
+	m.lastObserver++
+	id := m.lastObserver
+	m.observers = append(m.observers, subscription{id: id, o: o})
+
+	return func() {
+		for i, s := range m.observers {
+			if s.id == id {
+				m.observers = append(m.observers[:i:i], m.observers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Sends e to every observer
+func (m *Match) emit(e Event) {
+	for _, s := range m.observers {
+		s.o.OnEvent(e)
+	}
+}
+
+// Returns the player that won the round turn, 255 if tied between teams ('parda')
+func (m *Match) trickWinner(turn uint8) uint8 {
+	winner, highest := uint8(255), uint8(0)
+	for player := range m.Cards {
+		rank := m.Rules.Rank(m.Cards[player][turn])
+
+		switch {
+		case rank > highest:
+			winner, highest = uint8(player), rank
+		case rank == highest && winner != 255 && uint8(player)%2 != winner%2:
+			winner = 255
+		}
+	}
+	return winner
+}
+
+// Returns a player of the team that won the hand by playing its 3 rounds:
+//   - the first team to win 2 rounds
+//   - after a 'parda', the team that wins the next round
+//   - if a round is 'parda' after a won round, the team that won it
+//   - if every round is 'parda', the first player
+func (m *Match) handWinner() uint8 {
+	var wins [2]int
+	first := uint8(255)
+	for turn := range uint8(3) {
+		winner := m.trickWinner(turn)
+		if winner == 255 {
+			if first != 255 {
+				return first
+			}
+			continue
+		}
+		wins[winner%2]++
+		if first == 255 {
+			first = winner
+		}
+		if wins[winner%2] == 2 || turn > 0 && wins[(winner+1)%2] == 0 {
+			return winner
+		}
+	}
+	if first == 255 {
+		return 0
+	}
+	return first
+}
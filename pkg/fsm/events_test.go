@@ -0,0 +1,123 @@
+package fsm
+
+import (
+	"slices"
+	"testing"
+	"truco/pkg/truco"
+)
+
+func TestEvents(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+
+	var names []string
+	var events []Event
+	unsubscribe := m.Subscribe(ObserverFunc(func(e Event) { names = append(names, e.Name()) }))
+	m.Subscribe(ObserverFunc(func(e Event) { events = append(events, e) }))
+
+	_ = m.Ask(RequestTruco)
+	_ = m.Accept()
+	hands := [][]truco.Card{
+		{{N: 3, S: 'e'}, {N: 1, S: 'e'}, {N: 5, S: 'c'}},
+		{{N: 3, S: 'o'}, {N: 5, S: 'e'}, {N: 6, S: 'c'}},
+		{{N: 4, S: 'o'}, {N: 6, S: 'e'}, {N: 7, S: 'c'}},
+		{{N: 4, S: 'b'}, {N: 7, S: 'b'}, {N: 4, S: 'c'}},
+	}
+	for player := range hands {
+		_ = m.Play(hands[player][0])
+	}
+	unsubscribe()
+	for turn := 1; turn < 3; turn++ {
+		for player := range hands {
+			_ = m.Play(hands[player][turn])
+		}
+	}
+
+	expected := []string{"truco_raised", "bet_accepted", "card_played", "card_played", "card_played", "card_played", "trick_won"}
+	if !slices.Equal(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	// 3e and 3o tie: 'parda'. 1e wins the second round, and the hand
+	if trick := events[6].(TrickWon); trick.Player != 255 {
+		t.Errorf("expected parda, got %+v", trick)
+	}
+	if trick := events[11].(TrickWon); trick.Player != 0 || trick.Turn != 1 {
+		t.Errorf("expected player 1 to win the second round, got %+v", trick)
+	}
+	end, ok := events[len(events)-1].(MatchEnded)
+	if !ok || end.Winner != 0 || end.Points != 2 {
+		t.Errorf("expected player 1 to win 2 points, got %+v", events[len(events)-1])
+	}
+	if m.WinnerT != 0 {
+		t.Errorf("expected winner 0, got %d", m.WinnerT)
+	}
+}
+
+func TestEnvidoEvents(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	var events []Event
+	m.Subscribe(ObserverFunc(func(e Event) { events = append(events, e) }))
+
+	m.CPlayer = 2
+	_ = m.Ask(RequestEnvido)
+	_ = m.Accept()
+	for _, score := range []uint8{25, 30, 27, 20} {
+		_ = m.Announce(score)
+	}
+
+	if raised := events[0].(EnvidoRaised); raised.Player != 2 || raised.Bet != 2 {
+		t.Errorf("unexpected raise: %+v", raised)
+	}
+	if accepted := events[1].(BetAccepted); !accepted.IsEnvido || accepted.Asker != 2 {
+		t.Errorf("unexpected accept: %+v", accepted)
+	}
+	if announced := events[4].(EnvidoAnnounced); announced.Player != 2 || announced.Envido.String() != truco.EnvidoAtMost(30).String() {
+		t.Errorf("expected 'son buenas' of player 3, got %+v", announced)
+	}
+	resolved, ok := events[len(events)-1].(EnvidoResolved)
+	if !ok || resolved.Winner != 1 || resolved.Score != 30 || resolved.Points != 2 {
+		t.Errorf("expected player 2 to win the envido with 30, got %+v", events[len(events)-1])
+	}
+	if n := len(events); n != 7 {
+		t.Errorf("expected 7 events, got %d", n)
+	}
+}
+
+func TestHandWinner(t *testing.T) {
+	for _, test := range []struct {
+		hands    [][]truco.Card
+		expected uint8
+	}{
+		// every round is 'parda': the first player
+		{[][]truco.Card{
+			{{N: 4, S: 'e'}, {N: 5, S: 'e'}, {N: 6, S: 'e'}},
+			{{N: 4, S: 'b'}, {N: 5, S: 'b'}, {N: 6, S: 'b'}},
+			{{N: 4, S: 'o'}, {N: 5, S: 'o'}, {N: 6, S: 'o'}},
+			{{N: 4, S: 'c'}, {N: 5, S: 'c'}, {N: 6, S: 'c'}},
+		}, 0},
+		// player 2 wins the first round, 'parda' in the second
+		{[][]truco.Card{
+			{{N: 4, S: 'e'}, {N: 5, S: 'e'}, {N: 6, S: 'e'}},
+			{{N: 1, S: 'e'}, {N: 5, S: 'b'}, {N: 6, S: 'b'}},
+			{{N: 4, S: 'o'}, {N: 5, S: 'o'}, {N: 7, S: 'e'}},
+			{{N: 4, S: 'c'}, {N: 5, S: 'c'}, {N: 6, S: 'c'}},
+		}, 1},
+		// player 1 and player 4 win a round each, player 3 the last one
+		{[][]truco.Card{
+			{{N: 1, S: 'e'}, {N: 5, S: 'e'}, {N: 6, S: 'e'}},
+			{{N: 4, S: 'b'}, {N: 5, S: 'b'}, {N: 6, S: 'b'}},
+			{{N: 4, S: 'o'}, {N: 5, S: 'o'}, {N: 7, S: 'e'}},
+			{{N: 4, S: 'c'}, {N: 1, S: 'b'}, {N: 6, S: 'c'}},
+		}, 2},
+	} {
+		m := NewMatch(DEFAULT_HOUSE_RULES)
+		for turn := range 3 {
+			for player := range test.hands {
+				m.Cards[player][turn] = test.hands[player][turn]
+			}
+		}
+		if winner := m.handWinner(); winner != test.expected {
+			t.Errorf("expected winner %d, got %d", test.expected, winner)
+		}
+	}
+}
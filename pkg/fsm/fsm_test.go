@@ -6,7 +6,7 @@ import (
 )
 
 func TestTrucoFlow(t *testing.T) {
-	m := NewMatch()
+	m := NewMatch(DEFAULT_HOUSE_RULES)
 
 	// Initial state: PlayingState (id 1)
 	if m.stateId() != 1 {
@@ -42,7 +42,7 @@ func TestTrucoFlow(t *testing.T) {
 }
 
 func TestEnvidoFlow(t *testing.T) {
-	m := NewMatch()
+	m := NewMatch(DEFAULT_HOUSE_RULES)
 
 	m.CPlayer = 2
 	err := m.Ask(RequestEnvido) // envido
@@ -68,7 +68,7 @@ func TestEnvidoFlow(t *testing.T) {
 		t.Errorf("expected state 2, got %d", m.stateId())
 	}
 
-	// P0 starts announcing (CPlayerE returns first 255)
+	// P0 starts announcing (CPlayerE returns first undeclared)
 	if m.CPlayerE() != 0 {
 		t.Errorf("expected player 0 to announce, got %d", m.CPlayerE())
 	}
@@ -84,8 +84,8 @@ func TestEnvidoFlow(t *testing.T) {
 	if err != nil {
 		t.Fatalf("failed to announce 25: %v", err)
 	}
-	if m.Envidos[1] != 130 {
-		t.Fatalf("expected envido to be 130: '30 son buenas', got %d", m.Envidos[1])
+	if m.Envidos[1].String() != "<=30" {
+		t.Fatalf("expected envido to be '30 son buenas', got %s", m.Envidos[1])
 	}
 
 	// P2
@@ -99,8 +99,8 @@ func TestEnvidoFlow(t *testing.T) {
 	}
 
 	m.Fold()
-	if m.Envidos[2] != 130 {
-		t.Fatalf("expected envido to be 130: '30 son buenas', got %d", m.Envidos[2])
+	if m.Envidos[2].String() != "<=30" {
+		t.Fatalf("expected envido to be '30 son buenas', got %s", m.Envidos[2])
 	}
 
 	// P3 also folds
@@ -118,7 +118,7 @@ func TestEnvidoFlow(t *testing.T) {
 }
 
 func TestFoldTrucoAndEndstate(t *testing.T) {
-	m := NewMatch()
+	m := NewMatch(DEFAULT_HOUSE_RULES)
 	m.Ask(RequestTruco)
 	m.Fold() // P1 folds
 
@@ -149,3 +149,31 @@ func TestFoldTrucoAndEndstate(t *testing.T) {
 		t.Error("expected error announcing in EndState")
 	}
 }
+
+func TestRandomDeals(t *testing.T) {
+	deck := truco.NewDeck(42)
+	for i := range 50 {
+		deck.Reset()
+		deal, err := deck.Deal(NUM_PLAYERS, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		name := []string{"AR", "UY", "BR"}[i%3]
+		rules, _ := deal.Rules(name)
+		m := NewMatchRules(rules, DEFAULT_HOUSE_RULES)
+
+		for turn := range 3 {
+			for player := range NUM_PLAYERS {
+				if m.CState == m.End {
+					break
+				}
+				if err := m.Play(deal.Hands[player][turn]); err != nil {
+					t.Fatalf("%s deal %d: unexpected error: %v", name, i, err)
+				}
+			}
+		}
+		if m.CState != m.End || m.WinnerT > NUM_PLAYERS {
+			t.Errorf("%s deal %d: expected the hand to be over with a winner", name, i)
+		}
+	}
+}
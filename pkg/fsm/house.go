@@ -0,0 +1,171 @@
+package fsm
+
+import (
+	"fmt"
+	"slices"
+	"truco/pkg/truco"
+)
+
+// House rules: details that change from club to club, on top of the rules of the variant
+// (see truco.Ruleset). They are chosen when the match is created, and kept in its state.
+type HouseRules struct {
+	Flor              bool  // flor is played: it replaces the envido, for 3 points
+	FlorMandatory     bool  // a player with flor must declare it: hiding it is a lie (see Lied)
+	ContraflorAlResto bool  // a flor can be answered with 'contraflor al resto'
+	EnvidoFirstRound  bool  // envido can only be asked in the first round
+	LeyDeLaFalta      bool  // a falta envido while the leader is in 'malas' wins the game
+	ForcedSonBuenas   bool  // a player that can't beat the envido must say 'son buenas'
+	LiePenalty        bool  // lying about the envido gives it to the other team (see GetScore)
+	Points            uint8 // points to win the game: 15 or 30
+}
+
+// House rules of a match that doesn't choose any
+var DEFAULT_HOUSE_RULES = HouseRules{
+	EnvidoFirstRound: true,
+	ForcedSonBuenas:  true,
+	Points:           30,
+}
+
+// Returns an error if the house rules can't be played
+func (h HouseRules) Validate() error {
+	if h.Points != 15 && h.Points != 30 {
+		return fmt.Errorf("A game is played to 15 or 30 points, not %d", h.Points)
+	}
+	if !h.Flor && (h.FlorMandatory || h.ContraflorAlResto) {
+		return fmt.Errorf("Flor rules need flor")
+	}
+	return nil
+}
+
+// Sets the points each team had before this hand, for the falta envido
+func (m *Match) SetScores(team0, team1 uint8) error {
+	if team0 >= m.House.Points || team1 >= m.House.Points {
+		return fmt.Errorf("The game is over at %d points", m.House.Points)
+	}
+	m.Scores = [2]uint8{team0, team1}
+	return nil
+}
+
+// Points of a falta envido (or contraflor al resto) won by winner:
+// the points the leader needs to win the game.
+// With LeyDeLaFalta, while the leader is in 'malas' (first half of the game),
+// the winner gets the points they need to win the game.
+func (m *Match) faltaPoints(winner uint8) uint8 {
+	leader := max(m.Scores[0], m.Scores[1])
+	if m.House.LeyDeLaFalta && leader < m.House.Points/2 {
+		return m.House.Points - m.Scores[winner%2]
+	}
+	return m.House.Points - leader
+}
+
+// Returns true if player declared flor
+func (m *Match) declaredFlor(player uint8) bool {
+	return m.Flor&(1<<player) != 0
+}
+
+// Returns true if cards are a flor: 3 cards of the same suit,
+// or as the envido of the rules if it scores flor apart (see truco.Ruleset.HasFlor)
+func (m *Match) isFlor(cards []truco.Card) bool {
+	if len(cards) != 3 {
+		return false
+	}
+	if m.Rules.HasFlor() {
+		return m.Rules.Envido(cards) >= 200
+	}
+	return cards[0].S == cards[1].S && cards[1].S == cards[2].S
+}
+
+// Returns true if player held flor and didn't declare it, while it's mandatory
+func (m *Match) hidFlor(player uint8) bool {
+	return m.House.Flor && m.House.FlorMandatory && !m.declaredFlor(player) && m.isFlor(m.heldCards(player))
+}
+
+// Returns true if the current player can declare flor:
+// before playing their first card, and before the envido is played
+func (m *Match) canDeclareFlor() bool {
+	return m.House.Flor && m.Flor == 0 && m.cTurn() == 0 &&
+		m.Cards[m.CPlayer][0] == truco.NO_CARD && m.CEnvidoAsk == 255
+}
+
+// Returns true if the envido asked can be answered with a flor, that replaces it
+func (m *Match) canAnswerWithFlor() bool {
+	return m.House.Flor && m.Flor == 0 && m.IsEnvido && m.cTurn() == 0
+}
+
+// Player that answers the bet of CEnvidoAsk with a flor:
+// the first rival after them that can still declare it, as they didn't play a card
+func (m *Match) florResponder() uint8 {
+	for i := uint8(1); i < NUM_PLAYERS; i += 2 {
+		player := (m.CEnvidoAsk + i) % NUM_PLAYERS
+		if m.Cards[player][0] == truco.NO_CARD && !m.declaredFlor(player) {
+			return player
+		}
+	}
+	return (m.CEnvidoAsk + 1) % NUM_PLAYERS
+}
+
+// Declares flor for player: it replaces any envido, and the other team must answer it
+func (m *Match) declareFlor(player uint8) {
+	m.Flor |= 1 << player
+	m.Envidos[player] = truco.EnvidoFlor()
+	m.emit(EnvidoAnnounced{Player: player, Envido: m.Envidos[player]})
+
+	m.CEnvidoAsk = player
+	m.CEnvido = FLOR_POINTS
+	m.CEnvidoNo = FLOR_POINTS
+	m.IsEnvido = true
+	m.CState = m.Responding
+	m.emit(EnvidoRaised{Player: player, Bet: m.CEnvido})
+}
+
+// Answers the flor of the other team with player's flor
+func (m *Match) raiseFlor(player uint8, requestE AskRequest) {
+	m.Flor |= 1 << player
+	m.Envidos[player] = truco.EnvidoFlor()
+	m.emit(EnvidoAnnounced{Player: player, Envido: m.Envidos[player]})
+
+	// 'con flor me achico' gives the last bet, at least flor and a point
+	m.CEnvidoNo = max(m.CEnvido, FLOR_POINTS+1)
+	m.CEnvidoAsk = player
+	if requestE == RequestContraflorResto {
+		m.CEnvido = uint8(RequestFalta)
+	} else {
+		m.CEnvido = CONTRAFLOR_POINTS
+	}
+	m.emit(EnvidoRaised{Player: player, Bet: m.CEnvido})
+}
+
+// Returns true if the flor was answered with a contraflor
+func (m *Match) isFlorContested() bool {
+	return m.Flor != 0 && m.CEnvido != FLOR_POINTS
+}
+
+// Players with flor announce it: everyone else is done with the envido
+func (m *Match) startFlorAnnouncements() {
+	for player := range m.Envidos {
+		if m.declaredFlor(uint8(player)) {
+			m.Envidos[player] = truco.EnvidoAny()
+		} else {
+			m.Envidos[player] = truco.EnvidoNoFlor()
+		}
+	}
+}
+
+// Returns the flor actions of the responding team
+func (m *Match) florAnswers() []ValidAction {
+	var actions []ValidAction
+	if m.CEnvido == FLOR_POINTS {
+		actions = append(actions, CONTRAFLOR)
+	}
+	if m.House.ContraflorAlResto && m.CEnvido != uint8(RequestFalta) {
+		actions = append(actions, CONTRAFLOR_R)
+	}
+	return actions
+}
+
+// Returns true if every envido announcement was flor
+func (m *Match) isFlorOnly() bool {
+	return m.Flor != 0 && !slices.ContainsFunc(m.Envidos, func(e truco.EnvidoConstraint) bool {
+		return e.Kind == truco.ENVIDO_EXACT
+	})
+}
@@ -0,0 +1,132 @@
+package fsm
+
+import (
+	"fmt"
+	"slices"
+	"truco/pkg/truco"
+)
+
+// Envido truth check ("envido mentido"):
+// a player that announced an envido must hold it, and the cards they play
+// or show at the end of the hand ("mostrar") can prove they didn't.
+
+// Returns the cards player showed at the end of the hand
+func (m *Match) shown(player uint8) []truco.Card {
+	if int(player) >= len(m.Shown) {
+		return nil
+	}
+	return m.Shown[player]
+}
+
+// Returns the cards player is known to hold: the cards they played or showed
+func (m *Match) heldCards(player uint8) []truco.Card {
+	return slices.Concat(truco.RealCards(m.Cards[player]), m.shown(player))
+}
+
+// Returns the envido winner that must show their cards, and false if there is none:
+// envido wasn't played, or was won with a 'no quiero'
+func (m *Match) showingPlayer() (uint8, bool) {
+	if m.Envidos[0].IsAny() || !m.isEnvidoFull() {
+		return 0, false
+	}
+	_, winner := m.winnerE()
+	kind := m.Envidos[winner].Kind
+	return winner, kind == truco.ENVIDO_EXACT || kind == truco.ENVIDO_FLOR
+}
+
+// Returns true if player announced an envido (or flor)
+// that the cards they played or showed can't make.
+// 'Son buenas' is never a lie, but hiding a mandatory flor is (see HouseRules.FlorMandatory).
+func (m *Match) Lied(player uint8) bool {
+	if m.hidFlor(player) {
+		return true
+	}
+	return !m.fitsEnvido(player, m.heldCards(player))
+}
+
+// Returns true if player, holding held, can still make the envido (or flor) they announced
+func (m *Match) fitsEnvido(player uint8, held []truco.Card) bool {
+	declared := m.Envidos[player]
+	if declared.Kind != truco.ENVIDO_EXACT && declared.Kind != truco.ENVIDO_FLOR {
+		return true
+	}
+	if declared.Kind == truco.ENVIDO_FLOR && !m.Rules.HasFlor() {
+		// a flor of 3 cards of the same suit
+		return !slices.ContainsFunc(held, func(c truco.Card) bool { return c.S != held[0].S })
+	}
+
+	kCards := make([]truco.Card, 0, len(m.Cards)*len(m.Cards[0]))
+	for p := range m.Cards {
+		if p != int(player) {
+			kCards = append(kCards, m.heldCards(uint8(p))...)
+		}
+	}
+	return truco.CanReachEnvidoRules(m.Rules, declared, held, kCards)
+}
+
+// Returns the players that lied about their envido (see Lied)
+func (m *Match) Liars() []uint8 {
+	var liars []uint8
+	for player := range m.Envidos {
+		if m.Lied(uint8(player)) {
+			liars = append(liars, uint8(player))
+		}
+	}
+	return liars
+}
+
+// Returns an error if the envido winner can't show card at the end of the hand:
+//   - the hand isn't over, or nobody has to show their envido
+//   - the card is not in the deck (see ValidateCard), or someone else played or showed it
+//   - the card was already played or shown, or the winner already showed 3 cards
+func (m *Match) ValidateShow(card truco.Card) error {
+	if m.CState != m.End {
+		return fmt.Errorf("Cards are shown at the end of the hand")
+	}
+	winner, ok := m.showingPlayer()
+	if !ok {
+		return fmt.Errorf("Nobody has to show their envido")
+	}
+	if err := m.inDeck(card); err != nil {
+		return err
+	}
+
+	held := m.heldCards(winner)
+	if slices.Contains(held, card) {
+		return fmt.Errorf("Card %s is already known", card.ToString())
+	}
+	if len(held) >= 3 {
+		return fmt.Errorf("Player %d has no more cards to show", winner+1)
+	}
+	for p := range m.Cards {
+		if p != int(winner) && slices.Contains(m.heldCards(uint8(p)), card) {
+			return fmt.Errorf("Card %s is held by player %d", card.ToString(), p+1)
+		}
+	}
+	if hand := m.privateHand(winner); hand != nil && !slices.Contains(hand, card) {
+		return fmt.Errorf("Card %s is not in the hand of player %d", card.ToString(), winner+1)
+	}
+	if slices.Contains(m.privateCardsExcept(winner), card) {
+		return fmt.Errorf("Card %s is in the hand of another player", card.ToString())
+	}
+	return nil
+}
+
+// The envido winner shows card at the end of the hand ("mostrar")
+func (m *Match) Show(card truco.Card) error {
+	if err := m.ValidateShow(card); err != nil {
+		return err
+	}
+	winner, _ := m.showingPlayer()
+	if len(m.Shown) < NUM_PLAYERS {
+		m.Shown = slices.Grow(m.Shown, NUM_PLAYERS)[:NUM_PLAYERS]
+	}
+	m.Shown[winner] = append(m.Shown[winner], card)
+	return nil
+}
+
+// Returns true if the envido winner can still show a card
+func (m *Match) canShow() bool {
+	winner, ok := m.showingPlayer()
+	return ok && len(m.heldCards(winner)) < 3
+}
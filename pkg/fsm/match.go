@@ -1,6 +1,8 @@
 package fsm
 
 import (
+	"fmt"
+	"slices"
 	"truco/pkg/truco"
 )
 
@@ -13,6 +15,10 @@ const (
 	RequestReal   AskRequest = 3
 	RequestFalta  AskRequest = 255
 
+	RequestFlor            AskRequest = 4 // declares flor (see HouseRules.Flor)
+	RequestContraflor      AskRequest = 5 // answers a flor with a flor
+	RequestContraflorResto AskRequest = 6 // answers a flor with a flor, for the rest of the game
+
 	PLAY    ValidAction = "Carta"
 	ASK_T   ValidAction = "Truco"
 	ASK_RT  ValidAction = "Retruco"
@@ -25,32 +31,46 @@ const (
 	FOLD    ValidAction = "Al mazo"
 	FOLD_NQ ValidAction = "No quiero"
 	FOLD_SB ValidAction = "Son buenas"
-	// FLOR    ValidAction = "Flor" // TODO uy
+	SHOW    ValidAction = "Mostrar"
+	FLOR    ValidAction = "Flor"
+
+	CONTRAFLOR   ValidAction = "Contraflor"
+	CONTRAFLOR_R ValidAction = "Contraflor al resto"
 
 	NUM_PLAYERS = 4
+
+	FLOR_POINTS       = 3 // points of a flor, uncontested
+	CONTRAFLOR_POINTS = 6 // points of a contraflor
 )
 
 // FSM for a single match
 type Match struct {
 	// context
-	Cards      [][]truco.Card `json:"cards"`        // list of cards played: cards[player][turn]
-	CTruco     uint8          `json:"c_truco"`      // current truco bet (1-4)
-	CTrucoAsk  uint8          `json:"c_truco_ask"`  // who asked for the last truco bet
-	CPlayer    uint8          `json:"c_player"`     // player that should perform next action (not for envido)
-	Envidos    []uint8        `json:"envidos"`      // list of envidos declared per player: envidos[player] (default=255)
-	CEnvido    uint8          `json:"c_envido"`     // current envido bet 'quiero'
-	CEnvidoNo  uint8          `json:"c_envido_no"`  // current envido bet 'no quiero'
-	CEnvidoAsk uint8          `json:"c_envido_ask"` // who asked for the last envido bet
-	IsEnvido   bool           `json:"is_envido"`    // so we don't duplicate response actions and states: false=truco (default), true=envido
-	WinnerT    uint8          `json:"winner_t"`     // id of a player in the team that won truco, 255 if still playing
+	Cards      [][]truco.Card           `json:"cards"`        // list of cards played: cards[player][turn]
+	CTruco     uint8                    `json:"c_truco"`      // current truco bet (1-4)
+	CTrucoAsk  uint8                    `json:"c_truco_ask"`  // who asked for the last truco bet
+	CPlayer    uint8                    `json:"c_player"`     // player that should perform next action (not for envido)
+	Envidos    []truco.EnvidoConstraint `json:"envidos"`      // list of envidos declared per player: envidos[player] (default=any)
+	CEnvido    uint8                    `json:"c_envido"`     // current envido bet 'quiero'
+	CEnvidoNo  uint8                    `json:"c_envido_no"`  // current envido bet 'no quiero'
+	CEnvidoAsk uint8                    `json:"c_envido_ask"` // who asked for the last envido bet
+	IsEnvido   bool                     `json:"is_envido"`    // so we don't duplicate response actions and states: false=truco (default), true=envido
+	WinnerT    uint8                    `json:"winner_t"`     // id of a player in the team that won truco, 255 if still playing
+	Rules      truco.Ruleset            `json:"-"`            // variant played: deck, ranking, envido and bets
+	Private    *PrivateHands            `json:"private"`      // hands only the user knows, nil if not entered
+	Shown      [][]truco.Card           `json:"shown"`        // cards shown at the end of the hand: shown[player]
+	Eleven     bool                     `json:"eleven"`       // 'mão de onze': the team of CTrucoAsk plays against a team at 11 points
+	House      HouseRules               `json:"house"`        // house rules chosen for the match
+	Scores     [2]uint8                 `json:"scores"`       // points of each team before this hand (see SetScores)
+	Flor       uint8                    `json:"flor"`         // players that declared flor: bit i for player i
 	// players are indexed as the match order:
 	// 	- counter-clockwise, dealer last
 	//  - 255=none
 
 	// envidos are noted as:
-	//  - 0-33:    full score
-	//  - 100-133: 'son buenas': winner_env + 100
-	//  - 255:     undeclared
+	//  - exact:   full score
+	//  - at most: 'son buenas' against the winner envido
+	//  - any:     undeclared
 
 	// states
 	Playing    State `json:"-"` // can play a card or ask for truco
@@ -60,6 +80,9 @@ type Match struct {
 	CState     State `json:"-"` // current state
 
 	CStateId uint8 `json:"c_state_id"` // Helper for marshaling
+
+	observers    []subscription // see Subscribe
+	lastObserver int
 }
 
 type Score struct {
@@ -83,29 +106,35 @@ type State interface {
 	validActions() []ValidAction
 }
 
-// Returns an empty object, with binding to all states
-func NewMatch() *Match {
+// Returns an empty object, with binding to all states, for argentinian truco
+func NewMatch(house HouseRules) *Match {
+	return NewMatchRules(truco.ArgentineRules{}, house)
+}
+
+// Returns an empty object, with binding to all states, for the variant of rules
+func NewMatchRules(rules truco.Ruleset, house HouseRules) *Match {
 	cards := make([][]truco.Card, NUM_PLAYERS)
 	for i := range cards {
 		cards[i] = make([]truco.Card, 3)
 	}
 
-	envidos := make([]uint8, NUM_PLAYERS)
-	for i := range envidos {
-		envidos[i] = 255
-	}
+	envidos := make([]truco.EnvidoConstraint, NUM_PLAYERS)
+	shown := make([][]truco.Card, NUM_PLAYERS)
 
 	m := &Match{
 		Cards:      cards,
 		CTruco:     1,
 		CTrucoAsk:  255,
 		Envidos:    envidos,
+		Shown:      shown,
 		CEnvido:    0,
 		CEnvidoNo:  1,
 		CEnvidoAsk: 255,
 		CPlayer:    0,
 		IsEnvido:   false,
 		WinnerT:    255,
+		Rules:      rules,
+		House:      house,
 	}
 
 	m.bindStates()
@@ -128,6 +157,48 @@ func (m *Match) Play(card truco.Card) error {
 	return m.CState.play(card)
 }
 
+// Returns an error if the current player can't play card:
+//   - the card doesn't exist, is not in the deck of the rules (eg. the muestra), or was already played
+//   - the card is not in the private hand of the player, or is in someone else's (see SetPrivateHands)
+//   - no hand with the card reaches the envido the player declared, unless lies are penalised:
+//     then the card is played, and the lie is found (see Lied)
+func (m *Match) ValidateCard(card truco.Card) error {
+	if err := m.inDeck(card); err != nil {
+		return err
+	}
+
+	for player := range m.Cards {
+		if slices.Contains(m.Cards[player], card) {
+			return fmt.Errorf("Card %s was already played by player %d", card.ToString(), player+1)
+		}
+	}
+
+	if hand := m.privateHand(m.CPlayer); hand != nil && !slices.Contains(hand, card) {
+		return fmt.Errorf("Card %s is not in the hand of player %d", card.ToString(), m.CPlayer+1)
+	}
+	if slices.Contains(m.privateCardsExcept(m.CPlayer), card) {
+		return fmt.Errorf("Card %s is in the hand of another player", card.ToString())
+	}
+	if !m.House.LiePenalty && !m.fitsEnvido(m.CPlayer, append(m.heldCards(m.CPlayer), card)) {
+		return fmt.Errorf("Card %s doesn't fit the envido declared (%s)", card.ToString(), m.Envidos[m.CPlayer])
+	}
+	return nil
+}
+
+// Returns an error if card can't be dealt in the rules of the match
+func (m *Match) inDeck(card truco.Card) error {
+	if !slices.Contains(truco.ALL_CARDS, card) {
+		return fmt.Errorf("Card %s doesn't exist", card.ToString())
+	}
+	if card == m.Rules.Muestra() {
+		return fmt.Errorf("Card %s is the muestra", card.ToString())
+	}
+	if !slices.Contains(m.Rules.Deck(), card) {
+		return fmt.Errorf("Card %s is not in the deck", card.ToString())
+	}
+	return nil
+}
+
 // Ask for a bet increase, envido or truco
 func (m *Match) Ask(requestE AskRequest) error {
 	return m.CState.ask(requestE)
@@ -158,38 +229,178 @@ func (m *Match) ValidActions() []ValidAction {
 	return m.CState.validActions()
 }
 
-// List of all possible envido combinations
+// List of all possible envido combinations, in the rules and house rules of the match
 func (m *Match) ValidEnvidos() [][]ValidAction {
-	return [][]ValidAction{
-		{ASK_E},
-		{ASK_RE},
-		{ASK_FE},
-		{ASK_E, ASK_E},
-		{ASK_E, ASK_RE},
-		{ASK_E, ASK_E, ASK_RE},
+	var combos [][]ValidAction
+	if m.Rules.Bets().Envido > 0 {
+		combos = append(combos,
+			[]ValidAction{ASK_E},
+			[]ValidAction{ASK_RE},
+			[]ValidAction{ASK_FE},
+			[]ValidAction{ASK_E, ASK_E},
+			[]ValidAction{ASK_E, ASK_RE},
+			[]ValidAction{ASK_E, ASK_E, ASK_RE},
+		)
+	}
+	if m.House.Flor {
+		combos = append(combos, []ValidAction{FLOR}, []ValidAction{FLOR, CONTRAFLOR})
+		if m.House.ContraflorAlResto {
+			combos = append(combos, []ValidAction{FLOR, CONTRAFLOR_R}, []ValidAction{FLOR, CONTRAFLOR, CONTRAFLOR_R})
+		}
 	}
+	return combos
 }
 
 func (m *Match) GetStatsFilter() truco.FilterHands {
-	kCards := make([]truco.Card, 0, len(m.Cards)*len(m.Cards[0]))
-	for player := range m.Cards {
-		if player != int(m.CPlayer) {
-			for turn := range m.Cards[player] {
-				if m.Cards[player][turn].N != 0 {
-					kCards = append(kCards, m.Cards[player][turn])
-				}
+	return m.GetPlayerFilter(m.CPlayer)
+}
+
+// Filters of the tracker, as seen from the seat of viewer
+type ViewFilters struct {
+	Viewer uint8
+	Seat   uint8
+	Mine   truco.FilterHands // hands viewer could hold, for the rest of the table
+	Theirs truco.FilterHands // hands seat could hold, for viewer
+}
+
+// Returns the filters of viewer looking at seat.
+// Unlike GetStatsFilter, they don't change when the turn moves on.
+//
+// If viewer is the user (see SetPrivateHands), another seat can't hold
+// the private cards of the user and their partner.
+func (m *Match) GetViewFilters(viewer, seat uint8) ViewFilters {
+	theirs := m.GetPlayerFilter(seat)
+	if m.Private != nil && viewer == m.Private.Seat && seat != viewer {
+		for _, c := range m.privateCardsExcept(seat) {
+			if !slices.Contains(theirs.KCards, c) {
+				theirs.KCards = append(theirs.KCards, c)
 			}
 		}
 	}
 
+	return ViewFilters{
+		Viewer: viewer,
+		Seat:   seat,
+		Mine:   m.GetPlayerFilter(viewer),
+		Theirs: theirs,
+	}
+}
+
+// Returns what the table knows of the hand of player:
+// the cards they played, their envido, and the cards played by everyone else
+func (m *Match) GetPlayerFilter(player uint8) truco.FilterHands {
+	kCards := make([]truco.Card, 0, len(m.Cards)*len(m.Cards[0]))
+	for p := range m.Cards {
+		if p != int(player) {
+			kCards = append(kCards, truco.RealCards(m.Cards[p])...)
+		}
+	}
+
 	return truco.FilterHands{
 		KCards:  kCards,
-		MCards:  truco.RealCards(m.Cards[m.CPlayer]),
-		MEnvido: m.Envidos[m.CPlayer],
+		MCards:  truco.RealCards(m.Cards[player]),
+		MEnvido: m.knownEnvido(player),
 		// KEnvido: , // TODO is this useful?
 	}
 }
 
+// Returns what the table knows of the envido of player, as a constraint on their hand:
+//   - the envido they declared
+//   - EnvidoDeclined if they played a card without declaring a mandatory flor
+//   - any for a flor of rules that count it as envido: the constraint can't tell suits
+func (m *Match) knownEnvido(player uint8) truco.EnvidoConstraint {
+	envido := m.Envidos[player]
+	if m.House.FlorMandatory && envido.IsAny() && m.Cards[player][0] != truco.NO_CARD {
+		return truco.EnvidoDeclined()
+	}
+	if !m.Rules.HasFlor() && (envido.Kind == truco.ENVIDO_FLOR || envido.Kind == truco.ENVIDO_NO_FLOR) {
+		return truco.EnvidoAny()
+	}
+	return envido
+}
+
+// What the table knows of every seat: the cards they played and their envido
+func (m *Match) SeatViews() []truco.SeatView {
+	seats := make([]truco.SeatView, len(m.Cards))
+	for player := range m.Cards {
+		seats[player] = truco.SeatView{
+			Played: truco.RealCards(m.Cards[player]),
+			Envido: m.knownEnvido(uint8(player)),
+		}
+	}
+	return seats
+}
+
+// What viewer knows of every seat: as SeatViews,
+// and the private hands if viewer is the user (see SetPrivateHands)
+func (m *Match) SeatViewsOf(viewer uint8) []truco.SeatView {
+	seats := m.SeatViews()
+	if m.Private == nil || viewer != m.Private.Seat {
+		return seats
+	}
+	for player := range seats {
+		if hand := m.privateHand(uint8(player)); hand != nil {
+			seats[player].Hand = slices.Clone(hand)
+		}
+	}
+	return seats
+}
+
+// Rivals of the current player: the players right before and after them
+func (m *Match) Rivals() []uint8 {
+	return m.RivalsOf(m.CPlayer)
+}
+
+// Rivals of player: the players right before and after them
+func (m *Match) RivalsOf(player uint8) []uint8 {
+	return []uint8{(player - 1) % NUM_PLAYERS, (player + 1) % NUM_PLAYERS}
+}
+
+// What the current player knows of the hand, against the rival that plays right before them.
+//
+// Players play in a fixed order every turn: only the first player leads.
+// The tricks played are taken as they ended at the whole table, partners included.
+func (m *Match) GetPlayView() truco.PlayView {
+	rival := m.prevPlayer()
+
+	kCards := make([]truco.Card, 0, len(m.Cards)*len(m.Cards[0]))
+	for player := range m.Cards {
+		if player != int(m.CPlayer) && player != int(rival) {
+			kCards = append(kCards, truco.RealCards(m.Cards[player])...)
+		}
+	}
+
+	return truco.PlayView{
+		MPlayed:      truco.RealCards(m.Cards[m.CPlayer]),
+		OPlayed:      truco.RealCards(m.Cards[rival]),
+		KCards:       kCards,
+		OEnvido:      m.knownEnvido(rival),
+		IsMHandFirst: m.CPlayer < rival,
+		Results:      m.trickResults(m.CPlayer),
+		FixedOrder:   true,
+	}
+}
+
+// Results of the tricks played by the whole table, for the team of player:
+// 1 won, -1 lost, 0 parda
+func (m *Match) trickResults(player uint8) []int {
+	results := make([]int, 0, 3)
+	for turn := range uint8(len(m.Cards[NUM_PLAYERS-1])) {
+		if m.Cards[NUM_PLAYERS-1][turn].N == 0 {
+			break
+		}
+		switch winner := m.trickWinner(turn); {
+		case winner == 255:
+			results = append(results, 0)
+		case winner%2 == player%2:
+			results = append(results, 1)
+		default:
+			results = append(results, -1)
+		}
+	}
+	return results
+}
+
 // Truco player order
 func (m *Match) prevPlayer() uint8 {
 	return (m.CPlayer - 1) % NUM_PLAYERS
@@ -211,10 +422,10 @@ func (m *Match) cTurn() uint8 {
 }
 
 // Will return true if all players declared envido,
-// false if there is at least one didn't (envidos[i] == 255).
+// false if there is at least one didn't (envidos[i] is any).
 //
 // Note that it returns false if envido is never played.
-// (envidos array is initialized as full 255).
+// (envidos array is initialized as full any).
 func (m *Match) isEnvidoFull() bool {
 	return m.CPlayerE() == 255
 }
@@ -223,7 +434,7 @@ func (m *Match) isEnvidoFull() bool {
 // returns 255 if all players declared already
 func (m *Match) CPlayerE() int {
 	for i := range m.Envidos {
-		if m.Envidos[i] == 255 {
+		if m.Envidos[i].IsAny() {
 			return i
 		}
 	}
@@ -236,34 +447,148 @@ func (m *Match) CPlayerE() int {
 //   - If envido is 'no quiero', returns (0, score)
 func (m *Match) winnerE() (highest uint8, player uint8) {
 	highest = 0
-	if m.Envidos[0] == 255 && m.CEnvido != 0 {
+	if m.Envidos[0].IsAny() && m.CEnvido != 0 {
 		// envido asked, response was 'no quiero'
 		return highest, m.CEnvidoAsk
 	}
+	if m.isFlorOnly() {
+		// flor uncontested, or contraflor with 'no quiero'
+		return highest, m.CEnvidoAsk
+	}
 
 	for i := range m.Envidos {
 		cEnv := m.Envidos[i]
-		if cEnv == 255 {
+		if cEnv.IsAny() {
 			// unfinished round
 			break
 
-		} else if cEnv > 100 {
+		} else if cEnv.Kind != truco.ENVIDO_EXACT {
 			continue
 
-		} else if cEnv > highest {
-			highest = cEnv
+		} else if cEnv.Value > highest {
+			highest = cEnv.Value
 			player = uint8(i)
 		}
 	}
 	return highest, player
 }
 
+// Returns the score of the hand.
+// With LiePenalty, an envido winner that lied (see Lied) loses the envido:
+// it goes to the best honest announcement of the other team, or to the rival after the liar.
 func (m *Match) GetScore() *Score {
 	_, winnerE := m.winnerE()
+	if m.House.LiePenalty && m.Lied(winnerE) {
+		winnerE = m.honestWinnerE(winnerE)
+	}
 	return &Score{
 		winnerT: m.WinnerT,
-		pointsT: m.CTruco,
+		pointsT: m.trucoPoints(),
 		winnerE: winnerE,
-		pointsE: m.CEnvido,
+		pointsE: m.envidoWon(winnerE),
+	}
+}
+
+// Points of the envido for winner: the bet, the rest of the game for a falta,
+// or the 'no quiero' of a flor that was not contested to the end
+func (m *Match) envidoWon(winner uint8) uint8 {
+	if m.isFlorOnly() {
+		return m.CEnvidoNo
+	}
+	if m.CEnvido == uint8(RequestFalta) {
+		return m.faltaPoints(winner)
+	}
+	return m.CEnvido
+}
+
+// Returns true if envido can be asked in the current turn,
+// the first one unless the house rules say otherwise (see HouseRules.EnvidoFirstRound)
+func (m *Match) isEnvidoTurn() bool {
+	turn := m.cTurn()
+	return turn == 0 || (!m.House.EnvidoFirstRound && turn != 255)
+}
+
+// Points of the current truco bet, as in the bets of the rules
+func (m *Match) trucoPoints() uint8 {
+	return m.Rules.Bets().Truco[m.CTruco-1]
+}
+
+// Returns true if the current truco bet can't be raised.
+// A hand of eleven (see SetEleven) can't be raised.
+func (m *Match) isTrucoHighest() bool {
+	return m.Eleven || int(m.CTruco) >= len(m.Rules.Bets().Truco)
+}
+
+// Returns the actions that raise the truco bet, as named in the bets of the rules:
+// raises[i] asks for truco bet i+2
+func (m *Match) TrucoRaises() []ValidAction {
+	names := m.Rules.Bets().Raises
+	raises := make([]ValidAction, len(names))
+	for i, name := range names {
+		raises[i] = ValidAction(name)
+	}
+	return raises
+}
+
+// Returns true if action raises the truco bet in the rules of the match
+func (m *Match) IsTrucoRaise(action ValidAction) bool {
+	return slices.Contains(m.TrucoRaises(), action)
+}
+
+// Returns the action that asks for truco bet (2 or more), as named in the rules
+func (m *Match) TrucoRaise(bet uint8) ValidAction {
+	raises := m.TrucoRaises()
+	if bet < 2 || int(bet) > len(raises)+1 {
+		return ASK_T
+	}
+	return raises[bet-2]
+}
+
+// Starts a hand of eleven ('mão de onze'), for rules that have it (see truco.BetSchedule):
+// team is at 11 points, and sees the cards of the partner before deciding.
+//
+// The match waits for team to answer, as if the other team had asked for truco:
+// accept to play the hand for the second truco bet, without raises,
+// or fold to give the first bet to the other team.
+//
+// Returns an error if the rules don't have it, or the hand already started.
+func (m *Match) SetEleven(team uint8) error {
+	if !m.Rules.Bets().Eleven {
+		return fmt.Errorf("There's no hand of eleven in %s", m.Rules.Name())
+	}
+	if team > 1 {
+		return fmt.Errorf("Team %d doesn't exist", team+1)
+	}
+	if m.CState != m.Playing || m.Cards[0][0] != truco.NO_CARD || m.CTruco != 1 || m.CTrucoAsk != 255 {
+		return fmt.Errorf("The hand already started")
+	}
+
+	m.Eleven = true
+	m.CTrucoAsk = (team + 1) % 2
+	m.IsEnvido = false
+	m.CState = m.Responding
+	return nil
+}
+
+// Points an envido request adds to the bet, as in the bets of the rules
+func (m *Match) envidoPoints(requestE AskRequest) uint8 {
+	if requestE == RequestReal {
+		return m.Rules.Bets().RealEnvido
+	}
+	return m.Rules.Bets().Envido
+}
+
+// Winner of the envido in the team against liar
+func (m *Match) honestWinnerE(liar uint8) uint8 {
+	winner, highest := (liar+1)%NUM_PLAYERS, uint8(0)
+	for player, e := range m.Envidos {
+		p := uint8(player)
+		if p%2 == liar%2 || e.Kind != truco.ENVIDO_EXACT || m.Lied(p) {
+			continue
+		}
+		if e.Value > highest {
+			winner, highest = p, e.Value
+		}
 	}
+	return winner
 }
@@ -1,12 +1,13 @@
 package fsm
 
 import (
+	"slices"
 	"testing"
 	"truco/pkg/truco"
 )
 
 func TestNewMatch(t *testing.T) {
-	m := NewMatch()
+	m := NewMatch(DEFAULT_HOUSE_RULES)
 	if m.CTruco != 1 {
 		t.Errorf("expected CTruco 1, got %d", m.CTruco)
 	}
@@ -20,14 +21,14 @@ func TestNewMatch(t *testing.T) {
 		t.Errorf("expected NUM_PLAYERS players in envidos, got %d", len(m.Envidos))
 	}
 	for i, e := range m.Envidos {
-		if e != 255 {
-			t.Errorf("expected player %d envido to be 255, got %d", i, e)
+		if !e.IsAny() {
+			t.Errorf("expected player %d envido to be undeclared, got %s", i, e)
 		}
 	}
 }
 
 func TestPlayerOrder(t *testing.T) {
-	m := NewMatch()
+	m := NewMatch(DEFAULT_HOUSE_RULES)
 	m.CPlayer = 0
 	if m.nextPlayer() != 1 {
 		t.Errorf("next of 0 should be 1, got %d", m.nextPlayer())
@@ -46,7 +47,7 @@ func TestPlayerOrder(t *testing.T) {
 }
 
 func TestCTurn(t *testing.T) {
-	m := NewMatch()
+	m := NewMatch(DEFAULT_HOUSE_RULES)
 
 	// Turn 0: initially no cards played
 	if m.cTurn() != 0 {
@@ -88,7 +89,7 @@ func TestCTurn(t *testing.T) {
 }
 
 func TestEnvidoHelpers(t *testing.T) {
-	m := NewMatch()
+	m := NewMatch(DEFAULT_HOUSE_RULES)
 
 	if m.isEnvidoFull() {
 		t.Errorf("expected envido not full")
@@ -98,14 +99,14 @@ func TestEnvidoHelpers(t *testing.T) {
 		t.Errorf("expected player 0 to declare envido, got %d", m.CPlayerE())
 	}
 
-	m.Envidos[0] = 20
+	m.Envidos[0] = truco.EnvidoExact(20)
 	if m.CPlayerE() != 1 {
 		t.Errorf("expected player 1 to declare envido, got %d", m.CPlayerE())
 	}
 
-	m.Envidos[1] = 22
-	m.Envidos[2] = 122
-	m.Envidos[3] = 25
+	m.Envidos[1] = truco.EnvidoExact(22)
+	m.Envidos[2] = truco.EnvidoAtMost(22)
+	m.Envidos[3] = truco.EnvidoExact(25)
 
 	if !m.isEnvidoFull() {
 		t.Errorf("expected envido full")
@@ -122,3 +123,543 @@ func TestEnvidoHelpers(t *testing.T) {
 		t.Errorf("expected winner player 3, got %d", player)
 	}
 }
+
+func TestGetPlayView(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	m.Play(truco.Card{N: 4, S: 'e'})
+	m.Play(truco.Card{N: 7, S: 'o'})
+
+	// player 2, against player 1
+	view := m.GetPlayView()
+	if len(view.OPlayed) != 1 || view.OPlayed[0] != (truco.Card{N: 7, S: 'o'}) {
+		t.Errorf("expected rival to have played 7o, got %v", view.OPlayed)
+	}
+	if len(view.KCards) != 1 || view.KCards[0] != (truco.Card{N: 4, S: 'e'}) {
+		t.Errorf("expected 4e as known card, got %v", view.KCards)
+	}
+	if len(view.MPlayed) != 0 || view.IsMHandFirst {
+		t.Errorf("expected player 2 to answer with no cards played, got %v", view)
+	}
+
+	m.CPlayer = 0
+	if view = m.GetPlayView(); !view.IsMHandFirst {
+		t.Errorf("expected player 0 to lead against player 3")
+	}
+}
+
+// Seat 0 leads every trick in the tracker, whoever won the one before
+func TestSuggestCardAfterTrick(t *testing.T) {
+	mHand := truco.NewHand("4e 1e 3b")
+	for _, tc := range []struct {
+		name  string
+		trick []truco.Card
+	}{
+		{"seat 1 won", []truco.Card{{N: 4, S: 'e'}, {N: 7, S: 'o'}, {N: 5, S: 'c'}, {N: 6, S: 'c'}}},
+		{"seat 3 won", []truco.Card{{N: 4, S: 'e'}, {N: 5, S: 'c'}, {N: 6, S: 'c'}, {N: 1, S: 'b'}}},
+	} {
+		m := NewMatch(DEFAULT_HOUSE_RULES)
+		for _, c := range tc.trick {
+			if err := m.Play(c); err != nil {
+				t.Fatalf("%s: can't play %s: %v", tc.name, c.ToString(), err)
+			}
+		}
+
+		view := m.GetPlayView()
+		if len(view.Results) != 1 || view.Results[0] != -1 {
+			t.Errorf("%s: expected seat 0 to have lost the first trick, got %v", tc.name, view.Results)
+		}
+		res, err := truco.SuggestCard(mHand, view, truco.NO_CARD, 50, 1)
+		if err != nil {
+			t.Errorf("%s: expected a suggestion for seat 0, got %v", tc.name, err)
+		} else if len(res.Cards) != 2 {
+			t.Errorf("%s: expected to choose between 1e and 3b, got %v", tc.name, res.Cards)
+		}
+	}
+
+	// seat 1 answers seat 0 in the second trick, after its partner won the first
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	for _, c := range []truco.Card{{N: 4, S: 'e'}, {N: 5, S: 'c'}, {N: 6, S: 'c'}, {N: 1, S: 'b'}, {N: 3, S: 'e'}} {
+		m.Play(c)
+	}
+	view := m.GetPlayView()
+	if len(view.Results) != 1 || view.Results[0] != 1 || view.IsMHandFirst {
+		t.Errorf("expected seat 1 to answer after winning the first trick, got %v", view)
+	}
+	if _, err := truco.SuggestCard(truco.NewHand("5c 2o 2c"), view, truco.NO_CARD, 50, 1); err != nil {
+		t.Errorf("expected a suggestion for seat 1, got %v", err)
+	}
+}
+
+func TestSeatViews(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	m.Play(truco.Card{N: 4, S: 'e'})
+	m.Envidos[1] = truco.EnvidoExact(27)
+
+	seats := m.SeatViews()
+	if len(seats) != NUM_PLAYERS {
+		t.Fatalf("expected NUM_PLAYERS seats, got %d", len(seats))
+	}
+	if len(seats[0].Played) != 1 || seats[0].Played[0] != (truco.Card{N: 4, S: 'e'}) {
+		t.Errorf("expected player 0 to have played 4e, got %v", seats[0].Played)
+	}
+	if len(seats[1].Played) != 0 || seats[1].Envido.String() != "27" {
+		t.Errorf("expected player 1 with no cards and envido 27, got %v", seats[1])
+	}
+}
+
+func TestGetViewFilters(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	m.Play(truco.Card{N: 4, S: 'e'})
+	m.Play(truco.Card{N: 7, S: 'o'})
+
+	// the turn moved on, the view of player 0 looking at player 1 doesn't
+	f := m.GetViewFilters(0, 1)
+	if len(f.Mine.MCards) != 1 || f.Mine.MCards[0] != (truco.Card{N: 4, S: 'e'}) {
+		t.Errorf("expected viewer to hold 4e, got %v", f.Mine.MCards)
+	}
+	if len(f.Theirs.MCards) != 1 || f.Theirs.MCards[0] != (truco.Card{N: 7, S: 'o'}) {
+		t.Errorf("expected seat to hold 7o, got %v", f.Theirs.MCards)
+	}
+	if len(f.Theirs.KCards) != 1 || f.Theirs.KCards[0] != (truco.Card{N: 4, S: 'e'}) {
+		t.Errorf("expected seat not to hold 4e, got %v", f.Theirs.KCards)
+	}
+
+	if rivals := m.RivalsOf(0); rivals[0] != 3 || rivals[1] != 1 {
+		t.Errorf("expected players 3 and 1 as rivals of player 0, got %v", rivals)
+	}
+}
+
+func TestValidateCard(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	if err := m.Play(truco.NO_CARD); err == nil {
+		t.Errorf("expected error playing no card")
+	}
+	if err := m.Play(truco.Card{N: 8, S: 'e'}); err == nil {
+		t.Errorf("expected error playing a card that doesn't exist")
+	}
+
+	m.Envidos[0] = truco.EnvidoExact(33)
+	if err := m.Play(truco.Card{N: 4, S: 'e'}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Play(truco.Card{N: 4, S: 'e'}); err == nil || m.CPlayer != 1 {
+		t.Errorf("expected error playing a card twice")
+	}
+	for _, c := range []truco.Card{{N: 5, S: 'e'}, {N: 6, S: 'e'}, {N: 1, S: 'o'}} {
+		if err := m.Play(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// 4e 4b can't reach 33
+	if err := m.ValidateCard(truco.Card{N: 4, S: 'b'}); err == nil {
+		t.Errorf("expected error playing a card that doesn't fit the envido")
+	}
+	// 4e 7b 6b is still 33
+	if err := m.ValidateCard(truco.Card{N: 7, S: 'b'}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	// with the penalty, the lie is played to be found (see TestLied)
+	m.House.LiePenalty = true
+	if err := m.ValidateCard(truco.Card{N: 4, S: 'b'}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	m = NewMatchRules(truco.UruguayanRules{M: truco.Card{N: 4, S: 'c'}}, DEFAULT_HOUSE_RULES)
+	if err := m.Play(truco.Card{N: 4, S: 'c'}); err == nil {
+		t.Errorf("expected error playing the muestra")
+	}
+}
+
+func TestPrivateHands(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	hand := []truco.Card{{N: 1, S: 'e'}, {N: 7, S: 'o'}, {N: 3, S: 'c'}}
+	partner := []truco.Card{{N: 2, S: 'b'}, {N: 5, S: 'b'}, {N: 6, S: 'b'}}
+
+	if err := m.SetPrivateHands(0, hand[:2], nil); err == nil {
+		t.Errorf("expected error with 2 cards")
+	}
+	if err := m.SetPrivateHands(0, hand, hand); err == nil {
+		t.Errorf("expected error holding a card twice")
+	}
+	if err := m.SetPrivateHands(1, hand, partner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// player 1 can't play the cards of the user (2) nor of their partner (4)
+	if err := m.ValidateCard(truco.Card{N: 1, S: 'e'}); err == nil {
+		t.Errorf("expected error playing a card of the user")
+	}
+	if err := m.Play(truco.Card{N: 4, S: 'e'}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.ValidateCard(truco.Card{N: 4, S: 'o'}); err == nil {
+		t.Errorf("expected error playing a card out of the private hand")
+	}
+	if err := m.Play(truco.Card{N: 7, S: 'o'}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the private hands survive the encoding, but are off the public history
+	m, err := Decode(m.Encode())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Private == nil || len(m.Private.Partner) != 3 {
+		t.Fatalf("private hands lost in encoding")
+	}
+	if seats := m.SeatViews(); seats[1].Hand != nil || seats[3].Hand != nil {
+		t.Errorf("private hands in the public seat views")
+	}
+	if seats := m.SeatViewsOf(1); len(seats[1].Hand) != 3 || len(seats[3].Hand) != 3 || seats[0].Hand != nil {
+		t.Errorf("expected the private hands in the seat views of the user")
+	}
+	if kCards := m.GetPlayerFilter(2).KCards; len(kCards) != 2 {
+		t.Errorf("expected only played cards in the public filter, got %d", len(kCards))
+	}
+
+	// rivals of the user can't hold the private cards
+	mine := m.GetViewFilters(1, 2).Theirs.KCards
+	if len(mine) != 7 {
+		t.Errorf("expected 7 known cards for the user, got %d", len(mine))
+	}
+	if rival := m.GetViewFilters(0, 2).Theirs.KCards; len(rival) != 2 {
+		t.Errorf("expected 2 known cards for another viewer, got %d", len(rival))
+	}
+
+	mHand, view, ok := m.GetPrivatePlayView()
+	if !ok || len(mHand) != 3 {
+		t.Fatalf("expected the private hand")
+	}
+	if len(view.OPlayed) != 1 || len(view.MPlayed) != 1 || len(view.KCards) != 3 {
+		t.Errorf("unexpected play view: %+v", view)
+	}
+
+	// a hand that doesn't fit the cards played
+	m = NewMatch(DEFAULT_HOUSE_RULES)
+	_ = m.Play(truco.Card{N: 1, S: 'e'})
+	if err := m.SetPrivateHands(1, hand, nil); err == nil {
+		t.Errorf("expected error with a card played by another player")
+	}
+	if err := m.SetPrivateHands(0, partner, nil); err == nil {
+		t.Errorf("expected error with a played card out of the hand")
+	}
+}
+
+// The private view knows the envido of the rival as the table does
+func TestPrivatePlayViewFlor(t *testing.T) {
+	house := DEFAULT_HOUSE_RULES
+	house.Flor = true
+	hand := []truco.Card{{N: 1, S: 'e'}, {N: 7, S: 'o'}, {N: 3, S: 'c'}}
+
+	// a flor of argentinian rules doesn't tell the envido
+	m := NewMatch(house)
+	_ = m.Ask(RequestFlor)
+	_ = m.Accept()
+	if err := m.SetPrivateHands(1, hand, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, view, _ := m.GetPrivatePlayView()
+	if !view.OEnvido.IsAny() || view.OEnvido.String() != m.GetPlayView().OEnvido.String() {
+		t.Errorf("expected any envido of the rival, got %v", view.OEnvido)
+	}
+
+	// with mandatory flor, a rival that played without declaring it has none
+	house.FlorMandatory = true
+	m = NewMatch(house)
+	_ = m.Play(truco.Card{N: 4, S: 'e'})
+	_ = m.SetPrivateHands(1, hand, nil)
+	if _, view, _ = m.GetPrivatePlayView(); view.OEnvido.Kind != truco.ENVIDO_DECLINED {
+		t.Errorf("expected the rival to have no flor, got %v", view.OEnvido)
+	}
+}
+
+func TestLied(t *testing.T) {
+	house := DEFAULT_HOUSE_RULES
+	house.LiePenalty = true
+	m := NewMatch(house)
+	m.Envidos = []truco.EnvidoConstraint{truco.EnvidoExact(33), truco.EnvidoAtMost(33), truco.EnvidoExact(31), truco.EnvidoAtMost(33)}
+	for _, c := range []truco.Card{{N: 4, S: 'e'}, {N: 5, S: 'e'}, {N: 6, S: 'o'}, {N: 1, S: 'o'}} {
+		if err := m.Play(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if liars := m.Liars(); len(liars) != 0 {
+		t.Errorf("expected no liars, got %v", liars)
+	}
+
+	// 4e 4b can't make 33: player 1 lied
+	if err := m.Play(truco.Card{N: 4, S: 'b'}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !m.Lied(0) || m.Lied(1) {
+		t.Errorf("expected only player 1 to lie, got %v", m.Liars())
+	}
+
+	m.House.LiePenalty = false
+	if m.GetScore().winnerE != 0 {
+		t.Errorf("expected player 1 to win the envido without the house rule")
+	}
+	m.House.LiePenalty = true
+	if m.GetScore().winnerE != 1 {
+		t.Errorf("expected the envido for the other team, got player %d", m.GetScore().winnerE+1)
+	}
+	m.Envidos[3] = truco.EnvidoExact(32)
+	if m.GetScore().winnerE != 3 {
+		t.Errorf("expected the envido for the best honest rival, got player %d", m.GetScore().winnerE+1)
+	}
+}
+
+func TestShow(t *testing.T) {
+	// the lie is played, to be found (see ValidateCard)
+	house := DEFAULT_HOUSE_RULES
+	house.LiePenalty = true
+	m := NewMatch(house)
+	m.CEnvido = 2
+	m.Envidos = []truco.EnvidoConstraint{truco.EnvidoExact(27), truco.EnvidoExact(30), truco.EnvidoAtMost(30), truco.EnvidoAtMost(30)}
+	if err := m.Show(truco.Card{N: 7, S: 'b'}); err == nil {
+		t.Errorf("expected error showing before the end of the hand")
+	}
+
+	// player 2 plays 1o 2c 3e, that can't make 30
+	hands := [][]truco.Card{
+		{{N: 4, S: 'e'}, {N: 5, S: 'e'}, {N: 6, S: 'e'}},
+		{{N: 1, S: 'o'}, {N: 2, S: 'c'}, {N: 3, S: 'e'}},
+		{{N: 4, S: 'o'}, {N: 5, S: 'o'}, {N: 6, S: 'o'}},
+		{{N: 4, S: 'c'}, {N: 5, S: 'c'}, {N: 6, S: 'c'}},
+	}
+	for turn := range 3 {
+		for player := range hands {
+			_ = m.Play(hands[player][turn])
+		}
+	}
+	if m.CState != m.End {
+		t.Fatalf("expected the hand to be over")
+	}
+	if !m.Lied(1) {
+		t.Errorf("expected player 2 to lie: no 30 with 1o 2c 3e")
+	}
+	if slices.Contains(m.ValidActions(), SHOW) || m.ValidateShow(truco.Card{N: 7, S: 'b'}) == nil {
+		t.Errorf("expected nothing to show with 3 cards played")
+	}
+
+	m = NewMatch(DEFAULT_HOUSE_RULES)
+	m.CEnvido = 2
+	m.Envidos = []truco.EnvidoConstraint{truco.EnvidoExact(27), truco.EnvidoExact(30), truco.EnvidoAtMost(30), truco.EnvidoAtMost(30)}
+	m.Fold()
+	if !slices.Contains(m.ValidActions(), SHOW) {
+		t.Fatalf("expected player 2 to show their envido, got %v", m.ValidActions())
+	}
+	if err := m.Show(truco.Card{N: 7, S: 'b'}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Show(truco.Card{N: 7, S: 'b'}); err == nil {
+		t.Errorf("expected error showing a card twice")
+	}
+	if m.Lied(1) {
+		t.Errorf("7b can still make 30")
+	}
+	for _, c := range []truco.Card{{N: 1, S: 'o'}, {N: 2, S: 'c'}} {
+		if err := m.Show(c); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if !m.Lied(1) {
+		t.Errorf("expected player 2 to lie: no 30 with 7b 1o 2c")
+	}
+}
+
+func TestPaulista(t *testing.T) {
+	m := NewMatchRules(truco.PaulistaRules{V: truco.Card{N: 3, S: 'e'}}, DEFAULT_HOUSE_RULES)
+	if slices.Contains(m.ValidActions(), ASK_E) {
+		t.Errorf("expected no envido in truco paulista")
+	}
+	if err := m.Ask(RequestEnvido); err == nil {
+		t.Errorf("expected error asking for envido")
+	}
+
+	// truco, seis, nove, doze: each raise by the other team
+	for i, raise := range []ValidAction{"Truco", "Seis", "Nove", "Doze"} {
+		if !slices.Contains(m.ValidActions(), raise) {
+			t.Fatalf("expected %s in %v", raise, m.ValidActions())
+		}
+		if err := m.Ask(RequestTruco); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_ = m.Accept()
+		m.CPlayer = uint8(i+1) % NUM_PLAYERS
+	}
+	if err := m.Ask(RequestTruco); err == nil {
+		t.Errorf("expected error raising doze")
+	}
+	if points := m.GetScore().pointsT; points != 12 {
+		t.Errorf("expected 12 points, got %d", points)
+	}
+}
+
+func TestEleven(t *testing.T) {
+	if err := NewMatch(DEFAULT_HOUSE_RULES).SetEleven(0); err == nil {
+		t.Errorf("expected error without 'mão de onze' in the rules")
+	}
+
+	rules := truco.PaulistaRules{V: truco.Card{N: 3, S: 'e'}}
+	m := NewMatchRules(rules, DEFAULT_HOUSE_RULES)
+	if err := m.SetEleven(1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Play(truco.Card{N: 1, S: 'e'}); err == nil {
+		t.Errorf("expected error playing before deciding")
+	}
+	_ = m.Accept()
+	if slices.Contains(m.ValidActions(), ASK_T) || m.Ask(RequestTruco) == nil {
+		t.Errorf("expected no truco in a hand of eleven")
+	}
+	if points := m.GetScore().pointsT; points != 3 {
+		t.Errorf("expected 3 points, got %d", points)
+	}
+
+	// team 1 runs: team 0 gets a point
+	m = NewMatchRules(rules, DEFAULT_HOUSE_RULES)
+	_ = m.SetEleven(1)
+	m.Fold()
+	if score := m.GetScore(); score.winnerT%2 != 0 || score.pointsT != 1 {
+		t.Errorf("expected 1 point for team 1, got %d for player %d", score.pointsT, score.winnerT+1)
+	}
+	if err := m.SetEleven(0); err == nil {
+		t.Errorf("expected error after the hand started")
+	}
+}
+
+func TestFlor(t *testing.T) {
+	house := DEFAULT_HOUSE_RULES
+	house.Flor = true
+	m := NewMatch(house)
+	if err := m.Ask(RequestContraflor); err == nil {
+		t.Errorf("expected error answering a flor nobody declared")
+	}
+
+	// player 1 declares flor: the other team can only acknowledge it, or raise
+	if !slices.Contains(m.ValidActions(), FLOR) {
+		t.Fatalf("expected %s in %v", FLOR, m.ValidActions())
+	}
+	if err := m.Ask(RequestFlor); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if actions := m.ValidActions(); !slices.Equal(actions, []ValidAction{ACCEPT, CONTRAFLOR}) {
+		t.Errorf("expected to acknowledge or raise the flor, got %v", actions)
+	}
+	_ = m.Accept()
+	if score := m.GetScore(); score.winnerE != 0 || score.pointsE != FLOR_POINTS {
+		t.Errorf("expected %d points for player 1, got %d for player %d", FLOR_POINTS, score.pointsE, score.winnerE+1)
+	}
+	if slices.Contains(m.ValidActions(), FLOR) || m.Ask(RequestEnvido) == nil {
+		t.Errorf("expected no more flor nor envido")
+	}
+
+	// player 3 asks envido, player 4 answers with flor, player 3 with contraflor
+	m = NewMatch(house)
+	_ = m.Play(truco.Card{N: 4, S: 'e'})
+	_ = m.Play(truco.Card{N: 5, S: 'e'})
+	_ = m.Ask(RequestEnvido)
+	if !slices.Contains(m.ValidActions(), FLOR) {
+		t.Fatalf("expected %s in %v", FLOR, m.ValidActions())
+	}
+	_ = m.Ask(RequestFlor)
+	if err := m.Ask(RequestContraflorResto); err == nil {
+		t.Errorf("expected error raising al resto without the house rule")
+	}
+	if err := m.Ask(RequestContraflor); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.Flor != 1<<2|1<<3 || m.CEnvidoAsk != 2 {
+		t.Errorf("expected flor of players 3 and 4, raised by player 3")
+	}
+	_ = m.Accept()
+	if m.CState != m.Announcing || m.CPlayerE() != 2 {
+		t.Fatalf("expected player 3 to announce their flor")
+	}
+	_ = m.Announce(32)
+	_ = m.Announce(33)
+	if score := m.GetScore(); m.CState != m.Playing || score.winnerE != 3 || score.pointsE != CONTRAFLOR_POINTS {
+		t.Errorf("expected %d points for player 4, got %d for player %d", CONTRAFLOR_POINTS, score.pointsE, score.winnerE+1)
+	}
+
+	// 'con flor me achico'
+	m = NewMatch(house)
+	_ = m.Ask(RequestFlor)
+	_ = m.Ask(RequestContraflor)
+	m.Fold()
+	if score := m.GetScore(); score.winnerE != 1 || score.pointsE != FLOR_POINTS+1 {
+		t.Errorf("expected %d points for player 2, got %d for player %d", FLOR_POINTS+1, score.pointsE, score.winnerE+1)
+	}
+}
+
+func TestHouseRules(t *testing.T) {
+	if err := (HouseRules{Points: 20}).Validate(); err == nil {
+		t.Errorf("expected error with a game to 20 points")
+	}
+	if err := (HouseRules{FlorMandatory: true, Points: 30}).Validate(); err == nil {
+		t.Errorf("expected error with flor rules without flor")
+	}
+	if envidos := len(NewMatch(HouseRules{Flor: true, ContraflorAlResto: true, Points: 30}).ValidEnvidos()); envidos != 10 {
+		t.Errorf("expected 10 envido combinations with flor al resto, got %d", envidos)
+	}
+
+	// falta envido: the leader is at 20, player 2 wins
+	house := DEFAULT_HOUSE_RULES
+	m := NewMatch(house)
+	if err := m.SetScores(20, 30); err == nil {
+		t.Errorf("expected error with a finished game")
+	}
+	_ = m.SetScores(20, 10)
+	m.CEnvido = uint8(RequestFalta)
+	m.Envidos = []truco.EnvidoConstraint{truco.EnvidoExact(20), truco.EnvidoExact(30), truco.EnvidoAtMost(30), truco.EnvidoAtMost(30)}
+	if points := m.GetScore().pointsE; points != 10 {
+		t.Errorf("expected the 10 points the leader needs, got %d", points)
+	}
+	// ley de la falta: in 'malas', the winner gets the game
+	m.House.LeyDeLaFalta = true
+	_ = m.SetScores(14, 10)
+	if points := m.GetScore().pointsE; points != 20 {
+		t.Errorf("expected the 20 points player 2 needs, got %d", points)
+	}
+
+	// envido in any round, by any player
+	house.EnvidoFirstRound = false
+	m = NewMatch(house)
+	for _, c := range []truco.Card{{N: 4, S: 'e'}, {N: 5, S: 'e'}, {N: 6, S: 'e'}, {N: 7, S: 'e'}} {
+		_ = m.Play(c)
+	}
+	if !slices.Contains(m.ValidActions(), ASK_E) {
+		t.Fatalf("expected envido in the second round, got %v", m.ValidActions())
+	}
+	if err := m.Ask(RequestEnvido); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// without 'son buenas' forced, a losing envido is announced
+	house.ForcedSonBuenas = false
+	m = NewMatch(house)
+	_ = m.Play(truco.Card{N: 4, S: 'e'})
+	_ = m.Play(truco.Card{N: 5, S: 'e'})
+	_ = m.Ask(RequestEnvido)
+	_ = m.Accept()
+	_ = m.Announce(30)
+	_ = m.Announce(25)
+	if e := m.Envidos[1]; e.Kind != truco.ENVIDO_EXACT || e.Value != 25 {
+		t.Errorf("expected player 2 to announce 25, got %s", m.Envidos[1])
+	}
+
+	// hiding a mandatory flor is a lie
+	m = NewMatch(HouseRules{Flor: true, FlorMandatory: true, Points: 30})
+	for _, c := range []truco.Card{{N: 4, S: 'c'}, {N: 5, S: 'e'}, {N: 6, S: 'e'}, {N: 7, S: 'e'}} {
+		_ = m.Play(c)
+	}
+	if filter := m.GetPlayerFilter(0); filter.MEnvido.Kind != truco.ENVIDO_DECLINED {
+		t.Errorf("expected player 1 to decline the flor, got %s", filter.MEnvido)
+	}
+	m.Shown[0] = []truco.Card{{N: 5, S: 'c'}, {N: 6, S: 'c'}}
+	if !m.Lied(0) || m.Lied(1) {
+		t.Errorf("expected player 1 to lie by hiding the flor, got %v", m.Liars())
+	}
+}
@@ -17,26 +17,57 @@ func (p *PlayingState) play(card truco.Card) error {
 		return p.match.Play(card)
 	}
 
-	p.match.Cards[p.match.CPlayer][turn] = card
+	if err := p.match.ValidateCard(card); err != nil {
+		return err
+	}
+
+	player := p.match.CPlayer
+	p.match.Cards[player][turn] = card
 	p.match.CPlayer = p.match.nextPlayer()
+	p.match.emit(CardPlayed{Player: player, Card: card, Turn: turn})
+	if player == NUM_PLAYERS-1 {
+		p.match.emit(TrickWon{Turn: turn, Player: p.match.trickWinner(turn)})
+	}
 
-	turn = p.match.cTurn()
-	if turn == 255 {
+	if p.match.cTurn() == 255 {
 		// finished match
+		if p.match.WinnerT == 255 {
+			p.match.WinnerT = p.match.handWinner()
+		}
 		p.match.CState = p.match.End
-		return p.match.Play(card)
+		p.match.emit(MatchEnded{Winner: p.match.WinnerT, Points: p.match.trucoPoints()})
 	}
 	return nil
 }
 
 func (p *PlayingState) ask(requestE AskRequest) error {
+	if requestE == RequestFlor {
+		if !p.match.canDeclareFlor() {
+			return fmt.Errorf("You can't declare flor")
+		}
+		p.match.declareFlor(p.match.CPlayer)
+		return nil
+	} else if requestE == RequestContraflor || requestE == RequestContraflorResto {
+		return fmt.Errorf("There's no flor to answer")
+	}
+
 	if requestE != RequestTruco {
-		if p.match.cTurn() == 0 {
+		if p.match.Rules.Bets().Envido == 0 {
+			return fmt.Errorf("There's no envido in %s", p.match.Rules.Name())
+		}
+		if p.match.Flor != 0 {
+			return fmt.Errorf("The flor replaces the envido")
+		}
+		if p.match.isEnvidoTurn() {
 			if !p.match.IsEnvido { // first envido request
-				if p.match.CPlayer >= 2 { // only last two players can request it
+				if p.match.CEnvidoAsk != 255 {
+					return fmt.Errorf("Envido was already played")
+				}
+				if p.match.CPlayer >= 2 || p.match.cTurn() > 0 { // only last two players can request it in the first turn
 					p.match.CEnvidoAsk = p.match.CPlayer
-					p.match.CEnvido += uint8(requestE)
+					p.match.CEnvido += p.match.envidoPoints(requestE)
 					p.match.IsEnvido = true
+					p.match.emit(EnvidoRaised{Player: p.match.CPlayer, Bet: p.match.CEnvido})
 				} else {
 					return fmt.Errorf("You can't ask for envido")
 				}
@@ -47,9 +78,10 @@ func (p *PlayingState) ask(requestE AskRequest) error {
 					p.match.CEnvido = uint8(RequestFalta)
 					p.match.CEnvidoNo += 1 // TODO not correct
 				} else {
-					p.match.CEnvido += uint8(requestE)
+					p.match.CEnvido += p.match.envidoPoints(requestE)
 					p.match.CEnvidoNo += 1 // TODO not correct
 				}
+				p.match.emit(EnvidoRaised{Player: p.match.CPlayer, Bet: p.match.CEnvido})
 			}
 
 			p.match.CState = p.match.Responding
@@ -59,7 +91,7 @@ func (p *PlayingState) ask(requestE AskRequest) error {
 		}
 
 	} else {
-		if p.match.CTruco == 4 {
+		if p.match.isTrucoHighest() {
 			return fmt.Errorf("Truco is highest")
 		}
 
@@ -68,6 +100,7 @@ func (p *PlayingState) ask(requestE AskRequest) error {
 			p.match.IsEnvido = false
 			// p.match.cTruco changes in accept action
 			p.match.CState = p.match.Responding
+			p.match.emit(TrucoRaised{Player: p.match.CPlayer, Bet: p.match.CTruco + 1})
 			return nil
 		} else {
 			return fmt.Errorf("You can't ask for truco")
@@ -82,6 +115,7 @@ func (p *PlayingState) accept() error {
 func (p *PlayingState) fold() {
 	p.match.WinnerT = p.match.prevPlayer()
 	p.match.CState = p.match.End
+	p.match.emit(MatchEnded{Winner: p.match.WinnerT, Points: p.match.trucoPoints()})
 }
 
 func (p *PlayingState) announce(score uint8) error {
@@ -95,20 +129,17 @@ func (p *PlayingState) stateId() uint8 {
 func (p *PlayingState) validActions() []ValidAction {
 	actions := []ValidAction{PLAY, FOLD}
 
-	if p.match.CTruco == 1 {
-		actions = append(actions, ASK_T)
-	} else if p.match.CTrucoAsk%2 != p.match.CPlayer%2 {
-		switch p.match.CTruco {
-		case 2:
-			actions = append(actions, ASK_RT)
-		case 3:
-			actions = append(actions, ASK_V4)
-		}
+	if !p.match.isTrucoHighest() && (p.match.CTruco == 1 || p.match.CTrucoAsk%2 != p.match.CPlayer%2) {
+		actions = append(actions, p.match.TrucoRaise(p.match.CTruco+1))
+	}
+
+	if p.match.canDeclareFlor() {
+		actions = append(actions, FLOR)
 	}
 
-	if p.match.cTurn() == 0 {
+	if p.match.isEnvidoTurn() && p.match.Rules.Bets().Envido > 0 && p.match.Flor == 0 {
 		if !p.match.IsEnvido {
-			if p.match.CPlayer >= 2 && p.match.CEnvidoAsk == 255 {
+			if (p.match.CPlayer >= 2 || p.match.cTurn() > 0) && p.match.CEnvidoAsk == 255 {
 				// First time asking envido
 				actions = append(actions, ASK_E) // TODO: ASK_RE, ASK_FE
 			}
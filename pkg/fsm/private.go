@@ -0,0 +1,137 @@
+package fsm
+
+import (
+	"fmt"
+	"slices"
+	"truco/pkg/truco"
+)
+
+// Cards only the user of the tracker knows.
+// They are kept in the encoded state, but off the public history:
+// GetPlayerFilter and SeatViews never show them
+type PrivateHands struct {
+	Seat    uint8        `json:"seat"`    // seat of the user
+	Hand    []truco.Card `json:"hand"`    // the 3 cards of the user
+	Partner []truco.Card `json:"partner"` // the 3 cards of the partner, if shown
+}
+
+// Partner of player: the player in front of them
+func (m *Match) PartnerOf(player uint8) uint8 {
+	return (player + 2) % NUM_PLAYERS
+}
+
+// Stores the hand of the user, sitting at seat, and the hand of their partner (optional, nil if not shown).
+//
+// Returns an error if the hands don't fit the match:
+//   - a hand doesn't have 3 cards, or holds a card twice
+//   - a card doesn't exist, or is the muestra
+//   - a card was played by another player, or a card played by the seat is not in its hand
+func (m *Match) SetPrivateHands(seat uint8, hand, partner []truco.Card) error {
+	if seat >= NUM_PLAYERS {
+		return fmt.Errorf("Seat %d doesn't exist", seat+1)
+	}
+	if len(hand) != 3 {
+		return fmt.Errorf("Hand must have exactly 3 cards")
+	}
+	if len(partner) != 0 && len(partner) != 3 {
+		return fmt.Errorf("Hand of the partner must have exactly 3 cards")
+	}
+
+	owners := map[uint8][]truco.Card{seat: hand}
+	if len(partner) == 3 {
+		owners[m.PartnerOf(seat)] = partner
+	}
+
+	seen := make(map[truco.Card]bool, 6)
+	for _, c := range slices.Concat(hand, partner) {
+		if err := m.inDeck(c); err != nil {
+			return err
+		}
+		if seen[c] {
+			return fmt.Errorf("Card %s is held twice", c.ToString())
+		}
+		seen[c] = true
+	}
+
+	for player := range m.Cards {
+		h, known := owners[uint8(player)]
+		for _, c := range truco.RealCards(m.Cards[player]) {
+			if known && !slices.Contains(h, c) {
+				return fmt.Errorf("Card %s was played by player %d, but isn't in their hand", c.ToString(), player+1)
+			}
+			if !known && seen[c] {
+				return fmt.Errorf("Card %s was already played by player %d", c.ToString(), player+1)
+			}
+		}
+	}
+
+	m.Private = &PrivateHands{
+		Seat:    seat,
+		Hand:    slices.Clone(hand),
+		Partner: slices.Clone(partner),
+	}
+	return nil
+}
+
+// Returns the private hand of player, nil if unknown
+func (m *Match) privateHand(player uint8) []truco.Card {
+	if m.Private == nil {
+		return nil
+	}
+	switch {
+	case player == m.Private.Seat:
+		return m.Private.Hand
+	case player == m.PartnerOf(m.Private.Seat) && len(m.Private.Partner) == 3:
+		return m.Private.Partner
+	}
+	return nil
+}
+
+// Private cards of the user and their partner not held by player:
+// player can't hold them
+func (m *Match) privateCardsExcept(player uint8) []truco.Card {
+	if m.Private == nil {
+		return nil
+	}
+	var cards []truco.Card
+	for _, p := range []uint8{m.Private.Seat, m.PartnerOf(m.Private.Seat)} {
+		if p != player {
+			cards = append(cards, m.privateHand(p)...)
+		}
+	}
+	return cards
+}
+
+// What the user knows of the hand, against the rival that plays right before them:
+// as GetPlayView, from the seat of the user, with the private hands known.
+//
+// Returns the hand of the user, and false if they didn't enter it
+func (m *Match) GetPrivatePlayView() (truco.Hand, truco.PlayView, bool) {
+	if m.Private == nil {
+		return nil, truco.PlayView{}, false
+	}
+	seat := m.Private.Seat
+	rival := (seat - 1) % NUM_PLAYERS
+
+	kCards := make([]truco.Card, 0, len(m.Cards)*len(m.Cards[0]))
+	for player := range m.Cards {
+		if player != int(seat) && player != int(rival) {
+			kCards = append(kCards, truco.RealCards(m.Cards[player])...)
+		}
+	}
+	for _, c := range m.privateCardsExcept(seat) {
+		if !slices.Contains(kCards, c) {
+			kCards = append(kCards, c)
+		}
+	}
+
+	return truco.Hand(slices.Clone(m.Private.Hand)), truco.PlayView{
+		MPlayed:      truco.RealCards(m.Cards[seat]),
+		OPlayed:      truco.RealCards(m.Cards[rival]),
+		KCards:       kCards,
+		OEnvido:      m.knownEnvido(rival),
+		IsMHandFirst: seat < rival,
+		Results:      m.trickResults(seat),
+		FixedOrder:   true,
+	}, true
+}
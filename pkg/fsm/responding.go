@@ -18,14 +18,39 @@ func (r *RespondingState) play(card truco.Card) error {
 func (r *RespondingState) ask(requestE AskRequest) error {
 	// TODO allow "el envido va primero"
 
+	responder := r.match.florResponder()
+	switch requestE {
+	case RequestFlor:
+		if !r.match.canAnswerWithFlor() {
+			return fmt.Errorf("You can't declare flor")
+		}
+		r.match.declareFlor(responder)
+		return nil
+
+	case RequestContraflor, RequestContraflorResto:
+		if r.match.Flor == 0 || !r.match.IsEnvido {
+			return fmt.Errorf("There's no flor to answer")
+		}
+		if (requestE == RequestContraflor && r.match.CEnvido != FLOR_POINTS) ||
+			(requestE == RequestContraflorResto && (!r.match.House.ContraflorAlResto || r.match.CEnvido == uint8(RequestFalta))) {
+			return fmt.Errorf("You can't raise the flor")
+		}
+		r.match.raiseFlor(responder, requestE)
+		return nil
+	}
+
+	if r.match.IsEnvido && r.match.Flor != 0 && requestE != RequestTruco {
+		return fmt.Errorf("The flor replaces the envido")
+	}
 	if r.match.IsEnvido && requestE != RequestTruco {
 		// Envido re-raise
 		r.match.CEnvidoAsk = r.match.CPlayer
 		if requestE == RequestFalta {
 			r.match.CEnvido = uint8(RequestFalta)
 		} else {
-			r.match.CEnvido += uint8(requestE)
+			r.match.CEnvido += r.match.envidoPoints(requestE)
 		}
+		r.match.emit(EnvidoRaised{Player: r.match.CPlayer, Bet: r.match.CEnvido})
 		// TODO stay in Responding state, but now it's the other team's turn to respond
 		return nil
 	}
@@ -33,28 +58,60 @@ func (r *RespondingState) ask(requestE AskRequest) error {
 }
 
 func (r *RespondingState) accept() error {
-	if r.match.IsEnvido {
+	if r.match.IsEnvido && r.match.Flor != 0 && !r.match.isFlorContested() {
+		// the flor is acknowledged: it wins its points
+		r.closeFlor()
+		r.match.emit(EnvidoResolved{Winner: r.match.CEnvidoAsk, Points: r.match.CEnvidoNo})
+	} else if r.match.IsEnvido && r.match.Flor != 0 {
+		// players with flor announce it
+		r.match.startFlorAnnouncements()
 		r.match.CState = r.match.Announcing
+		r.match.emit(BetAccepted{Asker: r.match.CEnvidoAsk, IsEnvido: true, Bet: r.match.CEnvido})
+	} else if r.match.IsEnvido {
+		r.match.CState = r.match.Announcing
+		r.match.emit(BetAccepted{Asker: r.match.CEnvidoAsk, IsEnvido: true, Bet: r.match.CEnvido})
 	} else {
 		r.match.CTruco += 1
 		r.match.CState = r.match.Playing
+		r.match.emit(BetAccepted{Asker: r.match.CTrucoAsk, Bet: r.match.CTruco})
 	}
 
 	return nil
 }
 
 func (r *RespondingState) fold() {
-	if r.match.IsEnvido {
+	if r.match.IsEnvido && r.match.Flor != 0 {
+		// 'con flor me achico'
+		r.closeFlor()
+		r.match.emit(BetDeclined{Asker: r.match.CEnvidoAsk, IsEnvido: true, Points: r.match.CEnvidoNo})
+		r.match.emit(EnvidoResolved{Winner: r.match.CEnvidoAsk, Points: r.match.CEnvidoNo})
+	} else if r.match.IsEnvido {
 		r.match.IsEnvido = false
 		r.match.CState = r.match.Playing
+		r.match.emit(BetDeclined{Asker: r.match.CEnvidoAsk, IsEnvido: true, Points: r.match.CEnvidoNo})
+		r.match.emit(EnvidoResolved{Winner: r.match.CEnvidoAsk, Points: r.match.CEnvidoNo})
 	} else {
 		r.match.CState = r.match.End
-		r.match.WinnerT = r.match.CPlayer
+		// the asker, not the current player: in a hand of eleven (see SetEleven) nobody asked in turn
+		r.match.WinnerT = r.match.CTrucoAsk
 		// NOTE: this works only if we keep atomic ask-response:
 		// if we allow classic ask-ask-respond, it will not.
+		r.match.emit(BetDeclined{Asker: r.match.CTrucoAsk, Points: r.match.trucoPoints()})
+		r.match.emit(MatchEnded{Winner: r.match.WinnerT, Points: r.match.trucoPoints()})
 	}
 }
 
+// Ends a flor without announcements: players without flor are done with the envido
+func (r *RespondingState) closeFlor() {
+	for player := range r.match.Envidos {
+		if !r.match.declaredFlor(uint8(player)) {
+			r.match.Envidos[player] = truco.EnvidoNoFlor()
+		}
+	}
+	r.match.IsEnvido = false
+	r.match.CState = r.match.Playing
+}
+
 func (r *RespondingState) announce(score uint8) error {
 	return fmt.Errorf("You must respond")
 }
@@ -65,6 +122,16 @@ func (r *RespondingState) stateId() uint8 {
 
 func (r *RespondingState) validActions() []ValidAction {
 	actions := []ValidAction{ACCEPT, FOLD_NQ}
+	if r.match.IsEnvido && r.match.Flor != 0 {
+		if !r.match.isFlorContested() {
+			// a flor is not declined: it is acknowledged, or answered with a flor
+			actions = []ValidAction{ACCEPT}
+		}
+		return append(actions, r.match.florAnswers()...)
+	}
+	if r.match.canAnswerWithFlor() {
+		actions = append(actions, FLOR)
+	}
 	if r.match.IsEnvido {
 		if r.match.CEnvido < 255 {
 			if r.match.CEnvido < 3 {
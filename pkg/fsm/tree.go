@@ -0,0 +1,228 @@
+package fsm
+
+import (
+	"fmt"
+	"slices"
+	"truco/pkg/truco"
+)
+
+// Game tree of a match: every legal continuation of a state.
+//
+// ValidActions returns what the tracker offers, as labels ("Carta").
+// LegalActions returns concrete actions, that Apply can perform on the match.
+
+// A concrete action of a match
+type Action struct {
+	Kind  ValidAction // one of ValidActions
+	Card  truco.Card  // for PLAY and SHOW
+	Score uint8       // for ANNOUN
+}
+
+func (a Action) String() string {
+	switch a.Kind {
+	case PLAY, SHOW:
+		return string(a.Kind) + " " + a.Card.ToString()
+	case ANNOUN:
+		return fmt.Sprintf("%s %d", a.Kind, a.Score)
+	}
+	return string(a.Kind)
+}
+
+// Returns a deep copy of the match, without its observers
+func (m *Match) Clone() *Match {
+	c := *m
+	c.observers = nil
+	c.lastObserver = 0
+
+	c.Cards = make([][]truco.Card, len(m.Cards))
+	for p := range m.Cards {
+		c.Cards[p] = slices.Clone(m.Cards[p])
+	}
+	c.Envidos = make([]truco.EnvidoConstraint, len(m.Envidos))
+	for p, e := range m.Envidos {
+		c.Envidos[p] = truco.EnvidoConstraint{Kind: e.Kind, Value: e.Value, Values: slices.Clone(e.Values)}
+	}
+	c.Shown = make([][]truco.Card, len(m.Shown))
+	for p := range m.Shown {
+		c.Shown[p] = slices.Clone(m.Shown[p])
+	}
+	if m.Private != nil {
+		c.Private = &PrivateHands{
+			Seat:    m.Private.Seat,
+			Hand:    slices.Clone(m.Private.Hand),
+			Partner: slices.Clone(m.Private.Partner),
+		}
+	}
+
+	c.CStateId = m.CState.stateId()
+	c.bindStates()
+	c.setCState()
+	return &c
+}
+
+// Returns every action the match can take now:
+//   - a card for each card the current player can play, or the envido winner can show
+//   - each bet, response and fold
+//   - a score for each envido the player can announce: higher than the winner's
+//     (unless 'son buenas' is not forced, see HouseRules), and that fits the cards known to be theirs
+func (m *Match) LegalActions() []Action {
+	var actions []Action
+	for _, kind := range m.ValidActions() {
+		switch kind {
+		case PLAY, SHOW:
+			validate := m.ValidateCard
+			if kind == SHOW {
+				validate = m.ValidateShow
+			}
+			for _, c := range truco.ALL_CARDS {
+				if validate(c) == nil {
+					actions = append(actions, Action{Kind: kind, Card: c})
+				}
+			}
+
+		case ANNOUN:
+			for _, score := range m.announceScores() {
+				actions = append(actions, Action{Kind: kind, Score: score})
+			}
+
+		default:
+			actions = append(actions, Action{Kind: kind})
+		}
+	}
+	return actions
+}
+
+// Returns the envidos the player that must announce can announce
+func (m *Match) announceScores() []uint8 {
+	player := m.CPlayerE()
+	if player == 255 {
+		return nil
+	}
+	highest, _ := m.winnerE()
+
+	mCards := m.heldCards(uint8(player))
+	for _, c := range m.privateHand(uint8(player)) {
+		if !slices.Contains(mCards, c) {
+			mCards = append(mCards, c)
+		}
+	}
+	var kCards []truco.Card
+	for p := range m.Cards {
+		if p != player {
+			kCards = append(kCards, m.heldCards(uint8(p))...)
+		}
+	}
+	kCards = append(kCards, m.privateCardsExcept(uint8(player))...)
+
+	lo, hi := uint8(0), m.Rules.MaxEnvido()
+	if m.declaredFlor(uint8(player)) && m.Rules.HasFlor() {
+		// a flor is announced as its value
+		lo, hi = 200, 254
+	}
+
+	var scores []uint8
+	for score := lo; score <= hi; score++ {
+		if score > 7 && score < 20 || score <= highest && m.House.ForcedSonBuenas && !m.Envidos[0].IsAny() {
+			continue
+		}
+		if truco.CanReachEnvidoRules(m.Rules, truco.EnvidoExact(score), mCards, kCards) {
+			scores = append(scores, score)
+		}
+	}
+	return scores
+}
+
+// Performs action on the match.
+//
+// Returns an error if it's not one of LegalActions
+func (m *Match) Apply(action Action) error {
+	if !slices.Contains(m.LegalActions(), action) {
+		return fmt.Errorf("Action %s is not legal", action)
+	}
+
+	if m.IsTrucoRaise(action.Kind) {
+		return m.Ask(RequestTruco)
+	}
+	switch action.Kind {
+	case PLAY:
+		return m.Play(action.Card)
+	case SHOW:
+		return m.Show(action.Card)
+	case ASK_E:
+		return m.Ask(RequestEnvido)
+	case ASK_RE:
+		return m.Ask(RequestReal)
+	case ASK_FE:
+		return m.Ask(RequestFalta)
+	case FLOR:
+		return m.Ask(RequestFlor)
+	case CONTRAFLOR:
+		return m.Ask(RequestContraflor)
+	case CONTRAFLOR_R:
+		return m.Ask(RequestContraflorResto)
+	case ACCEPT:
+		return m.Accept()
+	case ANNOUN:
+		return m.Announce(action.Score)
+	case FOLD, FOLD_NQ, FOLD_SB:
+		m.Fold()
+		return nil
+	}
+	return fmt.Errorf("Action %s is not supported", action)
+}
+
+// Result of Walk
+type TreeStats struct {
+	Nodes          int // states visited
+	Transpositions int // states reached again by another path, not expanded twice
+	Leaves         int // states without legal actions
+	Cut            int // states left unexpanded at the depth limit
+}
+
+// Walks every legal continuation of the match, depth first, up to depth actions.
+// The match is not changed.
+//
+// visit is called on every new state with the actions that reached it,
+// and returns false to skip the continuations of the state.
+// A state reached again (same encoded state) is a transposition: it's not visited again,
+// unless it's reached with more depth left.
+func (m *Match) Walk(depth int, visit func(path []Action, state *Match) bool) TreeStats {
+	var stats TreeStats
+	seen := make(map[string]int) // encoded state: depth left when visited
+	var walk func(state *Match, path []Action)
+
+	walk = func(state *Match, path []Action) {
+		left := depth - len(path)
+		key := string(state.Encode())
+		if prev, ok := seen[key]; ok && prev >= left {
+			stats.Transpositions++
+			return
+		}
+		seen[key] = left
+
+		stats.Nodes++
+		if !visit(path, state) {
+			return
+		}
+		actions := state.LegalActions()
+		if len(actions) == 0 {
+			stats.Leaves++
+			return
+		}
+		if left == 0 {
+			stats.Cut++
+			return
+		}
+
+		for _, action := range actions {
+			next := state.Clone()
+			if err := next.Apply(action); err != nil {
+				continue
+			}
+			walk(next, append(slices.Clip(path), action))
+		}
+	}
+
+	walk(m.Clone(), nil)
+	return stats
+}
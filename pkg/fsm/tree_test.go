@@ -0,0 +1,101 @@
+package fsm
+
+import (
+	"slices"
+	"testing"
+	"truco/pkg/truco"
+)
+
+func TestClone(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	_ = m.SetPrivateHands(0, []truco.Card{{N: 1, S: 'e'}, {N: 7, S: 'o'}, {N: 3, S: 'c'}}, nil)
+	_ = m.Play(truco.Card{N: 1, S: 'e'})
+
+	c := m.Clone()
+	if string(c.Encode()) != string(m.Encode()) {
+		t.Errorf("expected the same state")
+	}
+	_ = c.Play(truco.Card{N: 4, S: 'b'})
+	c.Private.Hand[0] = truco.Card{N: 2, S: 'e'}
+	if m.Cards[1][0] != truco.NO_CARD || m.Private.Hand[0] != (truco.Card{N: 1, S: 'e'}) {
+		t.Errorf("expected the original match unchanged")
+	}
+}
+
+func TestLegalActions(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	actions := m.LegalActions()
+	if len(actions) != len(truco.ALL_CARDS)+2 {
+		t.Errorf("expected every card, truco and fold, got %d actions", len(actions))
+	}
+	if err := m.Apply(Action{Kind: ACCEPT}); err == nil {
+		t.Errorf("expected error accepting with nothing asked")
+	}
+	if err := m.Apply(Action{Kind: PLAY, Card: truco.Card{N: 7, S: 'o'}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := m.Apply(Action{Kind: PLAY, Card: truco.Card{N: 7, S: 'o'}}); err == nil {
+		t.Errorf("expected error playing a card twice")
+	}
+
+	// with 7e 6e 1o, player 1 can only announce 33
+	m = NewMatch(DEFAULT_HOUSE_RULES)
+	_ = m.SetPrivateHands(0, []truco.Card{{N: 7, S: 'e'}, {N: 6, S: 'e'}, {N: 1, S: 'o'}}, nil)
+	m.CPlayer = 2
+	for _, action := range []Action{{Kind: ASK_E}, {Kind: ACCEPT}} {
+		if err := m.Apply(action); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	expected := []Action{{Kind: ANNOUN, Score: 33}, {Kind: FOLD_SB}}
+	if actions := m.LegalActions(); !slices.Equal(actions, expected) {
+		t.Errorf("expected %v, got %v", expected, actions)
+	}
+	if err := m.Apply(Action{Kind: ANNOUN, Score: 33}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, action := range m.LegalActions() {
+		if action.Kind == ANNOUN && action.Score <= 33 {
+			t.Errorf("expected only envidos higher than 33, got %s", action)
+		}
+	}
+}
+
+func TestWalk(t *testing.T) {
+	m := NewMatch(DEFAULT_HOUSE_RULES)
+	m.CPlayer = 2
+	_ = m.Ask(RequestEnvido)
+	_ = m.Accept()
+	state := string(m.Encode())
+
+	// announcing 0 first is 'son buenas'
+	actions := m.LegalActions()
+	stats := m.Walk(1, func(path []Action, s *Match) bool { return true })
+	if stats.Nodes != len(actions) || stats.Transpositions != 1 || stats.Cut != len(actions)-1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if string(m.Encode()) != state {
+		t.Errorf("expected the match unchanged")
+	}
+
+	var paths [][]Action
+	stats = m.Walk(2, func(path []Action, s *Match) bool {
+		paths = append(paths, path)
+		return len(path) == 0
+	})
+	if stats.Nodes != len(paths) || stats.Cut != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	for _, path := range paths {
+		if len(path) > 1 {
+			t.Errorf("expected no continuations after a skipped state, got %v", path)
+		}
+	}
+
+	// at the end of the hand, nothing is left
+	m = NewMatch(DEFAULT_HOUSE_RULES)
+	m.Fold()
+	if stats := m.Walk(5, func([]Action, *Match) bool { return true }); stats.Nodes != 1 || stats.Leaves != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
@@ -13,6 +13,12 @@ func main() {
 	// 	fmt.Println("Successfully generated hand strength CSV in web/static/hand_stats.csv")
 	// }
 
+	// if err := truco.CreateHandStatsCSVBR("web/static/hand_stats_br.csv"); err != nil {
+	// 	fmt.Println("Error generating paulista hand strength CSV:", err)
+	// } else {
+	// 	fmt.Println("Successfully generated paulista hand strength CSV in web/static/hand_stats_br.csv")
+	// }
+
 	// if err := truco.CreatePairStatsCSV("web/static/hand_stats.csv", "web/static/pair_stats.csv"); err != nil {
 	// 	fmt.Println("Error generating pair stats:", err)
 	// } else {
@@ -27,7 +33,7 @@ func main() {
 	// oHand := []truco.Card{{N: 3, S: 'o'}}
 
 	// fmt.Println("soy mano, antes de jugar")
-	// hand.TrucoStrengthStats([]truco.Card{}, []truco.Card{}, 255, true, true).PPrint()
+	// hand.TrucoStrengthStats([]truco.Card{}, []truco.Card{}, truco.EnvidoAny(), true, true).PPrint()
 	// // fmt.Println()
 	// // fmt.Println("soy pie, antes de jugar")
 	// // hand.TrucoStrengthStats([]truco.Card{}, []truco.Card{}, false).PPrint()
@@ -46,12 +52,12 @@ func main() {
 
 	hand := truco.Hand{{N: 4, S: 'e'}, {N: 1, S: 'o'}, {N: 3, S: 'e'}}
 	for {
-		// hand.TrucoStrengthStats([]truco.Card{}, []truco.Card{}, 255, true, true).PPrint()
+		// hand.TrucoStrengthStats([]truco.Card{}, []truco.Card{}, truco.EnvidoAny(), true, true).PPrint()
 		start := time.Now()
 		// hand.TrucoStrengthUY()
 		// hand.TrucoStrength()
-		// hand.TrucoStrengthStats([]truco.Card{}, []truco.Card{}, 255, false, true).PPrint()
-		hand.TrucoStrengthStatsUY([]truco.Card{}, []truco.Card{{N: 11, S: 'e'}}, 200, false, true).PPrint()
+		// hand.TrucoStrengthStats([]truco.Card{}, []truco.Card{}, truco.EnvidoAny(), false, true).PPrint()
+		hand.TrucoStrengthStatsUY([]truco.Card{}, []truco.Card{{N: 11, S: 'e'}}, truco.EnvidoFlor(), false, truco.ReasonableOpponent{}).PPrint()
 		fmt.Println(time.Now().UnixMilli() - start.UnixMilli())
 		fmt.Println()
 	}
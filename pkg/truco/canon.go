@@ -0,0 +1,262 @@
+package truco
+
+import (
+	"iter"
+	"slices"
+	"truco/pkg/math"
+)
+
+// Canonicalisation of hands under suit isomorphism.
+//
+// Most of the brute force in this package (strength, stats, solver) only
+// looks at truco ranks and envido, so many hands give exactly the same results.
+// Instead of simulating every one of them we simulate a single representative,
+// and count it as many times as hands it stands for (its weight).
+//
+// Used by StrengthStats for the hands of the opponent, and by SuggestCard for the hands it solves.
+
+var SUITS = []uint8{'e', 'b', 'o', 'c'}
+
+// A hand that stands for Weight hands of the same class
+type WeightedHand struct {
+	Hand   Hand
+	Weight float64
+}
+
+// Position of the card in ALL_CARDS, used to sort cards in a canonical way
+func cardIndex(c Card) int {
+	return slices.Index(ALL_CARDS, c)
+}
+
+// Applies a suit relabelling to a card. The relabelling maps SUITS[i] to perm[i].
+func relabel(c Card, perm []uint8) Card {
+	return Card{c.N, perm[slices.Index(SUITS, c.S)]}
+}
+
+// SuitSymmetries returns all suit relabellings that leave the game unchanged,
+// given the known cards (kCards) and the muestra (m, NO_CARD for argentinian truco).
+//
+// A relabelling is a symmetry if:
+//   - it keeps the muestra in place (its suit defines the piezas)
+//   - it maps the set of known cards onto itself
+//   - every live card keeps its truco rank
+//
+// Envido only compares suits, so it is unchanged by any relabelling.
+// The identity is always returned first.
+//
+// Note that with no known cards, truco argentino has no symmetries:
+// every suit other than copa holds a special card (1e, 1b, 7e, 7o),
+// and copa can't be swapped with any of them.
+func SuitSymmetries(kCards []Card, m Card) [][]uint8 {
+	return suitSymmetries(kCards, RulesFor(m))
+}
+
+// SuitSymmetries, with the truco ranks and muestra of any ruleset
+func suitSymmetries(kCards []Card, r Ruleset) [][]uint8 {
+	m := r.Muestra()
+	known := make(map[Card]bool, len(kCards)+1)
+	for _, c := range kCards {
+		known[c] = true
+	}
+	if m != NO_CARD {
+		known[m] = true
+	}
+
+	syms := make([][]uint8, 0, 24)
+outer:
+	for perm := range permutationsSuits() {
+		if m != NO_CARD && relabel(m, perm) != m {
+			continue
+		}
+		for _, c := range ALL_CARDS {
+			rc := relabel(c, perm)
+			if known[c] {
+				if !known[rc] {
+					continue outer
+				}
+			} else if known[rc] || r.Rank(c) != r.Rank(rc) {
+				continue outer
+			}
+		}
+		syms = append(syms, perm)
+	}
+	return syms
+}
+
+// Symmetries (see SuitSymmetries) for a position of the solver:
+//   - fixed: cards in play (our hand, cards the rival played), that must stay in place
+//   - dead: cards nobody can play (played by other players), that may swap among themselves
+//
+// A position and its relabelling are then the same for the cards in play, card by card:
+// solving the canonical hand of the rival (see Hand.Canonical) gives exactly
+// the results of solving the hand itself, for each of our cards.
+func fixingSymmetries(fixed, dead []Card, r Ruleset) [][]uint8 {
+	syms := make([][]uint8, 0)
+	for _, perm := range suitSymmetries(dead, r) {
+		if !slices.ContainsFunc(fixed, func(c Card) bool { return relabel(c, perm) != c }) {
+			syms = append(syms, perm)
+		}
+	}
+	return syms
+}
+
+// Groups hands by their class under syms: one representative per class (see Hand.Canonical),
+// weighted by the amount of hands of the class, in the order of their first hand
+func canonicalClasses(hands []Hand, syms [][]uint8) []WeightedHand {
+	classes := make([]WeightedHand, 0, len(hands))
+	index := make(map[[3]Card]int, len(hands))
+	for _, hand := range hands {
+		canon, _ := hand.canonical(syms)
+		key := [3]Card(canon)
+		if i, ok := index[key]; ok {
+			classes[i].Weight++
+			continue
+		}
+		index[key] = len(classes)
+		classes = append(classes, WeightedHand{Hand: canon, Weight: 1})
+	}
+	return classes
+}
+
+// Yields all 24 permutations of SUITS, identity first
+func permutationsSuits() iter.Seq[[]uint8] {
+	return func(yield func([]uint8) bool) {
+		for _, a := range SUITS {
+			for _, b := range SUITS {
+				for _, c := range SUITS {
+					for _, d := range SUITS {
+						if a == b || a == c || a == d || b == c || b == d || c == d {
+							continue
+						}
+						if !yield([]uint8{a, b, c, d}) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// Canonical maps a hand to the representative of its class, given the known cards (kCards)
+// and the muestra (m, NO_CARD for argentinian truco), and returns the size of the class:
+// the amount of hands that are the same as this one under a suit symmetry (see SuitSymmetries).
+//
+// Truco and envido of the representative are exactly those of the hand,
+// so any stat can be computed once per class and weighted.
+// Cards in the representative are sorted as in ALL_CARDS.
+func (hand Hand) Canonical(kCards []Card, m Card) (Hand, int) {
+	return hand.canonical(SuitSymmetries(kCards, m))
+}
+
+func (hand Hand) canonical(syms [][]uint8) (Hand, int) {
+	var best []int
+	seen := make(map[[3]int]bool, len(syms))
+
+	for _, perm := range syms {
+		idx := make([]int, len(hand))
+		for i, c := range hand {
+			idx[i] = cardIndex(relabel(c, perm))
+		}
+		slices.Sort(idx)
+
+		var key [3]int
+		copy(key[:], idx)
+		seen[key] = true
+
+		if best == nil || slices.Compare(idx, best) < 0 {
+			best = idx
+		}
+	}
+
+	canon := make(Hand, len(best))
+	for i, ix := range best {
+		canon[i] = ALL_CARDS[ix]
+	}
+	return canon, len(seen)
+}
+
+// CanonicalHands returns one representative for every class of 3 card hands
+// that can be made with the live cards (not in kCards, not the muestra),
+// weighted by the size of its class. Weights add up to pick(live, 3).
+func CanonicalHands(kCards []Card, m Card) []WeightedHand {
+	syms := SuitSymmetries(kCards, m)
+	aCards := CardsExcluding(ALL_CARDS, append(slices.Clone(kCards), m))
+
+	hands := make([]WeightedHand, 0)
+	seen := make(map[[3]Card]bool)
+	for cs := range math.Combinations(aCards, 3) {
+		canon, weight := Hand(cs).canonical(syms)
+		key := [3]Card{canon[0], canon[1], canon[2]}
+		if !seen[key] {
+			seen[key] = true
+			hands = append(hands, WeightedHand{Hand: canon, Weight: float64(weight)})
+		}
+	}
+	return hands
+}
+
+// TrucoClasses returns one representative for every class of 3 card hands,
+// made from aCards, that share the same truco ranks (given m, NO_CARD for argentinian truco),
+// weighted by the amount of hands in that class. Weights add up to pick(len(aCards), 3).
+//
+// This is a coarser isomorphism than Canonical: cards of the same rank
+// are interchangeable, but envido is not kept.
+// Use it only when the result does not depend on envido (eg. TrucoBeats).
+//
+// Representatives are sorted by truco rank, highest first.
+func TrucoClasses(aCards []Card, m Card) []WeightedHand {
+	return trucoClasses(aCards, RulesFor(m))
+}
+
+// TrucoClasses, with the truco ranks of any ruleset
+func trucoClasses(aCards []Card, rules Ruleset) []WeightedHand {
+	byRank := make(map[uint8][]Card)
+	ranks := make([]uint8, 0, len(RANKS))
+	for _, c := range aCards {
+		r := rules.Rank(c)
+		if byRank[r] == nil {
+			ranks = append(ranks, r)
+		}
+		byRank[r] = append(byRank[r], c)
+	}
+	slices.Sort(ranks)
+	slices.Reverse(ranks)
+
+	classes := make([]WeightedHand, 0)
+	for i, r0 := range ranks {
+		for j := i; j < len(ranks); j++ {
+			for k := j; k < len(ranks); k++ {
+				r1, r2 := ranks[j], ranks[k]
+
+				// amount of cards we need of each rank
+				need := map[uint8]int{r0: 0, r1: 0, r2: 0}
+				need[r0]++
+				need[r1]++
+				need[r2]++
+
+				weight := 1.0
+				hand := make(Hand, 0, 3)
+				for r, n := range need {
+					if len(byRank[r]) < n {
+						weight = 0
+						break
+					}
+					weight *= float64(math.PickC(len(byRank[r]), n))
+				}
+				if weight == 0 {
+					continue
+				}
+
+				// pick the first cards of each rank, in order
+				used := make(map[uint8]int, 3)
+				for _, r := range []uint8{r0, r1, r2} {
+					hand = append(hand, byRank[r][used[r]])
+					used[r]++
+				}
+				classes = append(classes, WeightedHand{Hand: hand, Weight: weight})
+			}
+		}
+	}
+	return classes
+}
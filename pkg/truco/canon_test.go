@@ -0,0 +1,184 @@
+package truco
+
+import (
+	"slices"
+	"testing"
+	"truco/pkg/math"
+)
+
+func TestSuitSymmetries(t *testing.T) {
+	syms := SuitSymmetries([]Card{}, NO_CARD)
+	if len(syms) != 1 {
+		t.Errorf("Expected only identity without known cards, got %d symmetries", len(syms))
+	}
+
+	// 7o and 7c are gone: oro and copa can be swapped
+	syms = SuitSymmetries([]Card{{7, 'o'}, {7, 'c'}}, NO_CARD)
+	if len(syms) != 2 {
+		t.Errorf("Expected 2 symmetries, got %d", len(syms))
+	}
+	if !slices.Equal(syms[0], SUITS) {
+		t.Errorf("Expected identity first, got %v", syms[0])
+	}
+
+	// muestra suit can't move
+	syms = SuitSymmetries([]Card{{1, 'e'}, {1, 'b'}, {7, 'e'}, {7, 'o'}}, Card{4, 'c'})
+	for _, perm := range syms {
+		if perm[3] != 'c' {
+			t.Errorf("Expected muestra suit to be fixed, got %v", perm)
+		}
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	kCards := []Card{{7, 'o'}, {7, 'c'}}
+
+	a, wa := Hand{{1, 'o'}, {3, 'o'}, {4, 'e'}}.Canonical(kCards, NO_CARD)
+	b, wb := Hand{{1, 'c'}, {3, 'c'}, {4, 'e'}}.Canonical(kCards, NO_CARD)
+	if !slices.Equal(a, b) || wa != 2 || wb != 2 {
+		t.Errorf("Expected same class of 2 hands, got %v (%d) and %v (%d)", a, wa, b, wb)
+	}
+	if a.Envido() != (Hand{{1, 'c'}, {3, 'c'}, {4, 'e'}}).Envido() {
+		t.Errorf("Expected canonical hand to keep envido")
+	}
+
+	// 1e is unique: no other hand in its class
+	_, w := Hand{{1, 'e'}, {3, 'o'}, {4, 'c'}}.Canonical([]Card{}, NO_CARD)
+	if w != 1 {
+		t.Errorf("Expected class of 1 hand, got %d", w)
+	}
+}
+
+func TestCanonicalHandsWeights(t *testing.T) {
+	tests := []struct {
+		kCards []Card
+		m      Card
+	}{
+		{[]Card{}, NO_CARD},
+		{[]Card{{7, 'o'}, {7, 'c'}}, NO_CARD},
+		{[]Card{{1, 'e'}, {1, 'b'}, {7, 'e'}, {7, 'o'}}, NO_CARD},
+		{[]Card{{1, 'e'}, {1, 'b'}, {7, 'e'}, {7, 'o'}}, Card{4, 'c'}},
+	}
+
+	for _, tt := range tests {
+		hands := CanonicalHands(tt.kCards, tt.m)
+		live := len(CardsExcluding(ALL_CARDS, append(slices.Clone(tt.kCards), tt.m)))
+
+		var total float64
+		for _, h := range hands {
+			total += h.Weight
+		}
+		if total != float64(math.PickC(live, 3)) {
+			t.Errorf("kCards=%v m=%v: expected weights to add up to %v, got %v", tt.kCards, tt.m, math.PickC(live, 3), total)
+		}
+	}
+}
+
+func TestTrucoClasses(t *testing.T) {
+	classes := TrucoClasses(ALL_CARDS, NO_CARD)
+	var total float64
+	for _, c := range classes {
+		total += c.Weight
+		if c.Hand[0].Truco() < c.Hand[1].Truco() || c.Hand[1].Truco() < c.Hand[2].Truco() {
+			t.Errorf("Expected representative %v to be sorted by truco", c.Hand)
+		}
+	}
+	if total != float64(math.PickC(40, 3)) {
+		t.Errorf("Expected weights to add up to %v, got %v", math.PickC(40, 3), total)
+	}
+
+	// 1e 1b + any 3: 4 hands
+	i := slices.IndexFunc(classes, func(c WeightedHand) bool {
+		return c.Hand[0] == Card{1, 'e'} && c.Hand[1] == Card{1, 'b'} && c.Hand[2].N == 3
+	})
+	if i == -1 || classes[i].Weight != 4 {
+		t.Errorf("Expected class 1e 1b 3 with 4 hands")
+	}
+}
+
+// TrucoStrength simulates one hand per class: should be the same as playing every hand
+func TestTrucoStrengthClasses(t *testing.T) {
+	for _, mHand := range []Hand{NewHand("1e 7o 3c"), NewHand("4e 4b 5c"), NewHand("12o 1c 2e")} {
+		mPerms := math.PermutationsRaw(mHand, 3)
+		oPerms := math.PermutationsRaw(CardsExcluding(ALL_CARDS, mHand), 3)
+		var score int
+		for mH := range mPerms {
+			for oH := range oPerms {
+				score += TrucoBeats(Hand(mPerms[mH]), Hand(oPerms[oH]), NO_CARD)
+			}
+		}
+		expected := float32(score) / (math.PickC(37, 3) * 36.0)
+
+		if got := mHand.TrucoStrength(); got != expected {
+			t.Errorf("Hand %v: expected strength %f, got %f", mHand, expected, got)
+		}
+	}
+}
+
+// StrengthStats simulates one opponent hand per class: should be the same as playing every hand
+func TestStrengthStatsClasses(t *testing.T) {
+	r := ArgentineRules{}
+	mHand, oCards := NewHand("4e 5e 6e"), []Card{{7, 'o'}, {7, 'c'}}
+	oHands := possibleOHands(r, mHand, []Card{}, oCards, EnvidoAny())
+	classes := canonicalClasses(oHands, fixingSymmetries(mHand, oCards, r))
+	if len(classes) >= len(oHands) {
+		t.Errorf("Expected less classes than hands, got %d for %d hands", len(classes), len(oHands))
+	}
+
+	for _, model := range []OpponentModel{UniformOpponent{}, BestResponseOpponent{}} {
+		stats := mHand.StrengthStats(r, []Card{}, oCards, EnvidoAny(), true, model)
+		var score, count int
+		for _, mPerm := range math.PermutationsRaw(mHand, 3) {
+			for _, oHand := range oHands {
+				w, c := model.Versus(mPerm, oHand, []Card{}, r, true)
+				score += w
+				count += c
+			}
+		}
+		if stats.StrengthAll != float32(score)/float32(count) {
+			t.Errorf("%T: expected strength %f, got %f", model, float32(score)/float32(count), stats.StrengthAll)
+		}
+	}
+}
+
+// StrengthStatsPlayed with one card played: only the orders that start with it, as in StrengthStats
+func TestStrengthStatsPlayed(t *testing.T) {
+	r := ArgentineRules{}
+	mHand, mPlayed := NewHand("4e 7o 1c"), []Card{{7, 'o'}}
+	all := mHand.StrengthStats(r, []Card{}, []Card{}, EnvidoAny(), true, UniformOpponent{})
+	played := mHand.StrengthStatsPlayed(r, mPlayed, []Card{}, []Card{}, EnvidoAny(), true, UniformOpponent{})
+
+	if len(played.Perms) != 2 {
+		t.Fatalf("Expected 2 orders, got %d", len(played.Perms))
+	}
+	for i, perm := range played.Perms {
+		if perm[0] != mPlayed[0] {
+			t.Errorf("Expected %s to start with %s", perm.ToString(), mPlayed[0].ToString())
+		}
+		j := slices.IndexFunc(all.Perms, func(h Hand) bool { return slices.Equal(h, perm) })
+		if played.WinsPerm[i] != all.WinsPerm[j] || played.StrengthPermAbs[i] != all.StrengthPermAbs[j] {
+			t.Errorf("%s: expected wins %f (strength %f), got %f (%f)", perm.ToString(), all.WinsPerm[j], all.StrengthPermAbs[j], played.WinsPerm[i], played.StrengthPermAbs[i])
+		}
+	}
+}
+
+// SuggestCard solves one rival hand per class: the canonical hand has the same minimax values
+func TestMinimaxClasses(t *testing.T) {
+	m := Card{4, 'c'}
+	mHand, view := NewHand("5e 6e 12c"), PlayView{KCards: []Card{{1, 'o'}, {7, 'o'}, {1, 'b'}, {7, 'b'}}, IsMHandFirst: true}
+	syms := fixingSymmetries(mHand, view.KCards, RulesFor(m))
+	if len(syms) < 2 {
+		t.Fatalf("Expected oro and basto to be swapped, got %v", syms)
+	}
+
+	for _, oHand := range rivalRange(RulesFor(m), mHand, view) {
+		canon, _ := oHand.canonical(syms)
+		for _, c := range mHand {
+			a, _ := Minimax(mHand, oHand, m, true, []Card{c}, []Card{})
+			b, _ := Minimax(mHand, canon, m, true, []Card{c}, []Card{})
+			if a.Value != b.Value {
+				t.Fatalf("%s vs %s and %s: expected the same value playing %s", mHand.ToString(), oHand.ToString(), canon.ToString(), c.ToString())
+			}
+		}
+	}
+}
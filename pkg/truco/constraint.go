@@ -0,0 +1,216 @@
+package truco
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// What we know of the envido of a player, from what they declared.
+//
+// Envido values are as Hand.Envido for argentinian truco, or Hand.EnvidoUY for uruguayan truco
+// (flor is 220-247: flor of 20-47).
+//
+// The zero value is EnvidoAny: nothing is known.
+type EnvidoConstraint struct {
+	Kind   EnvidoKind
+	Value  uint8   // for ENVIDO_EXACT, ENVIDO_AT_MOST, ENVIDO_AT_LEAST
+	Values []uint8 // for ENVIDO_ONE_OF
+}
+
+type EnvidoKind uint8
+
+const (
+	ENVIDO_ANY      EnvidoKind = iota // nothing is known
+	ENVIDO_EXACT                      // declared a number
+	ENVIDO_AT_MOST                    // 'son buenas': lower or equal than the winner's envido
+	ENVIDO_AT_LEAST                   //
+	ENVIDO_ONE_OF                     // one of a set of values
+	ENVIDO_FLOR                       // declared flor, of any value
+	ENVIDO_NO_FLOR                    // has no flor
+	ENVIDO_DECLINED                   // didn't declare when they could: flor must be declared, so no flor
+)
+
+// ENVIDO_NO_FLOR and ENVIDO_DECLINED allow the same hands, but are known differently:
+// no flor is said at the table (eg. answering a flor), and only counts in rules with flor,
+// while declined is deduced from a silence (eg. a mandatory flor not declared).
+
+// Nothing is known of the envido
+func EnvidoAny() EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_ANY}
+}
+
+// Declared envido v
+func EnvidoExact(v uint8) EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_EXACT, Value: v}
+}
+
+// Envido v or lower, eg. 'son buenas' against v
+func EnvidoAtMost(v uint8) EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_AT_MOST, Value: v}
+}
+
+// Envido v or higher
+func EnvidoAtLeast(v uint8) EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_AT_LEAST, Value: v}
+}
+
+// Envido is one of vs
+func EnvidoOneOf(vs ...uint8) EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_ONE_OF, Values: vs}
+}
+
+// Declared flor, of unknown value
+func EnvidoFlor() EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_FLOR}
+}
+
+// Has no flor
+func EnvidoNoFlor() EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_NO_FLOR}
+}
+
+// Didn't declare envido nor flor when they could
+func EnvidoDeclined() EnvidoConstraint {
+	return EnvidoConstraint{Kind: ENVIDO_DECLINED}
+}
+
+// Returns true if a hand with this envido (as Hand.Envido or Hand.EnvidoUY) fits the constraint
+func (e EnvidoConstraint) Allows(envido uint8) bool {
+	isFlor := envido >= 200 && envido != 255
+
+	switch e.Kind {
+	case ENVIDO_EXACT:
+		return envido == e.Value
+	case ENVIDO_AT_MOST:
+		return envido <= e.Value
+	case ENVIDO_AT_LEAST:
+		return envido >= e.Value
+	case ENVIDO_ONE_OF:
+		return slices.Contains(e.Values, envido)
+	case ENVIDO_FLOR:
+		return isFlor
+	case ENVIDO_NO_FLOR, ENVIDO_DECLINED:
+		return !isFlor
+	}
+	return true
+}
+
+// Returns true if nothing is known of the envido
+func (e EnvidoConstraint) IsAny() bool {
+	return e.Kind == ENVIDO_ANY
+}
+
+// Text format of the constraint, as used in JSON:
+//   - any
+//   - 27: exact
+//   - <=27, >=27
+//   - 27|28|33: one of
+//   - flor, noflor, declined
+func (e EnvidoConstraint) String() string {
+	switch e.Kind {
+	case ENVIDO_EXACT:
+		return strconv.Itoa(int(e.Value))
+	case ENVIDO_AT_MOST:
+		return "<=" + strconv.Itoa(int(e.Value))
+	case ENVIDO_AT_LEAST:
+		return ">=" + strconv.Itoa(int(e.Value))
+	case ENVIDO_ONE_OF:
+		vs := make([]string, 0, len(e.Values))
+		for _, v := range e.Values {
+			vs = append(vs, strconv.Itoa(int(v)))
+		}
+		return strings.Join(vs, "|")
+	case ENVIDO_FLOR:
+		return "flor"
+	case ENVIDO_NO_FLOR:
+		return "noflor"
+	case ENVIDO_DECLINED:
+		return "declined"
+	}
+	return "any"
+}
+
+// Parses the text format of EnvidoConstraint.String.
+// An empty string is EnvidoAny.
+func ParseEnvidoConstraint(s string) (EnvidoConstraint, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "", "any":
+		return EnvidoAny(), nil
+	case "flor":
+		return EnvidoFlor(), nil
+	case "noflor":
+		return EnvidoNoFlor(), nil
+	case "declined":
+		return EnvidoDeclined(), nil
+	}
+
+	if v, ok := strings.CutPrefix(s, "<="); ok {
+		n, err := parseEnvidoValue(v)
+		return EnvidoAtMost(n), err
+	} else if v, ok := strings.CutPrefix(s, ">="); ok {
+		n, err := parseEnvidoValue(v)
+		return EnvidoAtLeast(n), err
+	} else if strings.Contains(s, "|") {
+		vs := make([]uint8, 0)
+		for _, v := range strings.Split(s, "|") {
+			n, err := parseEnvidoValue(v)
+			if err != nil {
+				return EnvidoAny(), err
+			}
+			vs = append(vs, n)
+		}
+		return EnvidoOneOf(vs...), nil
+	}
+
+	n, err := parseEnvidoValue(s)
+	return EnvidoExact(n), err
+}
+
+func parseEnvidoValue(s string) (uint8, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("Invalid envido '%s'", s)
+	}
+	return uint8(n), nil
+}
+
+func (e EnvidoConstraint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
+}
+
+// Reads the text format (see ParseEnvidoConstraint)
+func (e *EnvidoConstraint) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseEnvidoConstraint(s)
+	if err != nil {
+		return err
+	}
+	*e = parsed
+	return nil
+}
+
+// Returns the constraint of an old uint8 code of the envido, as kept before EnvidoConstraint:
+//   - 0-99:    exact
+//   - 100-199: 'son buenas', at most (code-100)
+//   - 200:     flor
+//   - 201-254: exact flor
+//   - 255:     any
+func EnvidoFromCode(code uint8) EnvidoConstraint {
+	if code == 255 {
+		return EnvidoAny()
+	} else if code == 200 {
+		return EnvidoFlor()
+	} else if code > 200 {
+		return EnvidoExact(code)
+	} else if code >= 100 {
+		return EnvidoAtMost(code - 100)
+	}
+	return EnvidoExact(code)
+}
@@ -0,0 +1,90 @@
+package truco
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestEnvidoConstraintAllows(t *testing.T) {
+	tests := []struct {
+		constraint EnvidoConstraint
+		allowed    []uint8
+		rejected   []uint8
+	}{
+		{EnvidoAny(), []uint8{0, 7, 27, 33, 227}, []uint8{}},
+		{EnvidoExact(27), []uint8{27}, []uint8{26, 28, 227}},
+		{EnvidoAtMost(27), []uint8{0, 7, 27}, []uint8{28, 33, 227}},
+		{EnvidoAtLeast(27), []uint8{27, 33, 227}, []uint8{0, 26}},
+		{EnvidoOneOf(20, 33), []uint8{20, 33}, []uint8{21, 27}},
+		{EnvidoFlor(), []uint8{220, 247}, []uint8{0, 33, 37}},
+		{EnvidoNoFlor(), []uint8{0, 33, 37}, []uint8{220, 247}},
+		{EnvidoDeclined(), []uint8{0, 33, 37}, []uint8{220, 247}},
+	}
+
+	for _, tt := range tests {
+		for _, e := range tt.allowed {
+			if !tt.constraint.Allows(e) {
+				t.Errorf("Expected %s to allow %d", tt.constraint, e)
+			}
+		}
+		for _, e := range tt.rejected {
+			if tt.constraint.Allows(e) {
+				t.Errorf("Expected %s to reject %d", tt.constraint, e)
+			}
+		}
+	}
+}
+
+func TestParseEnvidoConstraint(t *testing.T) {
+	for _, s := range []string{"any", "27", "<=27", ">=27", "20|27|33", "flor", "noflor", "declined"} {
+		e, err := ParseEnvidoConstraint(s)
+		if err != nil {
+			t.Errorf("Unexpected error parsing '%s': %v", s, err)
+		} else if e.String() != s {
+			t.Errorf("Expected '%s' after parsing, got '%s'", s, e)
+		}
+	}
+
+	if e, err := ParseEnvidoConstraint(""); err != nil || !e.IsAny() {
+		t.Errorf("Expected empty string to be any, got %s (%v)", e, err)
+	}
+	for _, s := range []string{"abc", "<=", "27|", "300"} {
+		if _, err := ParseEnvidoConstraint(s); err == nil {
+			t.Errorf("Expected error parsing '%s'", s)
+		}
+	}
+}
+
+func TestEnvidoConstraintJSON(t *testing.T) {
+	data, err := json.Marshal([]EnvidoConstraint{EnvidoExact(27), EnvidoAtMost(30), EnvidoAny()})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var strs []string
+	if err := json.Unmarshal(data, &strs); err != nil || !slices.Equal(strs, []string{"27", "<=30", "any"}) {
+		t.Errorf("Expected envidos as strings, got %s", data)
+	}
+
+	var envidos []EnvidoConstraint
+	if err := json.Unmarshal(data, &envidos); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(envidos) != 3 || envidos[0].String() != "27" || envidos[1].String() != "<=30" || !envidos[2].IsAny() {
+		t.Errorf("Unexpected envidos after round trip: %v", envidos)
+	}
+
+	if err := json.Unmarshal([]byte(`[27]`), &envidos); err == nil {
+		t.Errorf("Expected error reading an envido that is not text")
+	}
+}
+
+func TestEnvidoFromCode(t *testing.T) {
+	// old uint8 codes, as saved by previous versions
+	expected := []string{"27", "<=30", "flor", "227", "any"}
+	for i, code := range []uint8{27, 130, 200, 227, 255} {
+		if e := EnvidoFromCode(code); e.String() != expected[i] {
+			t.Errorf("Expected legacy envido %d to be %s, got %s", code, expected[i], e)
+		}
+	}
+}
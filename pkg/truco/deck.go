@@ -0,0 +1,145 @@
+package truco
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// Dealer of the cards.
+//
+// A Deck is shuffled from a seed: the same seed deals the same cards, in the same order.
+// Simulations, practice hands and randomised tests deal from a Deck, so any deal can be replayed.
+
+// A shuffled deck, dealt from the top
+type Deck struct {
+	all   []Card // cards of the deck when full
+	cards []Card // cards left, top first
+	rng   *rand.Rand
+}
+
+// Returns the 40 cards (ALL_CARDS), shuffled with seed
+func NewDeck(seed uint64) *Deck {
+	return NewDeckOf(ALL_CARDS, seed)
+}
+
+// Returns a deck of cards, shuffled with seed.
+// Eg. the cards still unseen, to deal the hands of the rivals.
+func NewDeckOf(cards []Card, seed uint64) *Deck {
+	d := &Deck{
+		all: slices.Clone(cards),
+		rng: rand.New(rand.NewPCG(seed, seed)),
+	}
+	d.Reset()
+	return d
+}
+
+// Puts every card back, and shuffles again: the next deal of the same seed
+func (d *Deck) Reset() {
+	d.cards = append(d.cards[:0], d.all...)
+	d.rng.Shuffle(len(d.cards), func(a, b int) { d.cards[a], d.cards[b] = d.cards[b], d.cards[a] })
+}
+
+// Amount of cards left
+func (d *Deck) Len() int {
+	return len(d.cards)
+}
+
+// Takes n cards from the top.
+//
+// Returns an error if there are less than n cards left.
+func (d *Deck) Draw(n int) ([]Card, error) {
+	if n > len(d.cards) {
+		return nil, fmt.Errorf("Can't draw %d cards, %d left", n, len(d.cards))
+	}
+	drawn := slices.Clone(d.cards[:n])
+	d.cards = d.cards[n:]
+	return drawn, nil
+}
+
+// Cards dealt for a hand
+type Deal struct {
+	Hands   []Hand // hands[seat], in the order of the match
+	Muestra Card   // card turned up: muestra in UY, vira in BR. NO_CARD if none
+}
+
+// Returns the ruleset named name for the deal (see NewRuleset), with its muestra
+func (d Deal) Rules(name string) (Ruleset, error) {
+	return NewRuleset(name, d.Muestra)
+}
+
+// Cards known before dealing, for Deck.DealKnown
+type KnownCards struct {
+	Hands   [][]Card // cards each seat is known to hold: hands[seat], up to 3. nil for none
+	Muestra Card     // muestra if known, NO_CARD to turn one up
+}
+
+// Deals 3 cards to each of seats (see TABLE_SIZES),
+// and turns up a muestra after the hands if muestra is true.
+//
+// Returns an error if seats is not a table size, or the deck is short.
+func (d *Deck) Deal(seats int, muestra bool) (Deal, error) {
+	return d.DealKnown(seats, muestra, KnownCards{})
+}
+
+// Deals as Deal, with the known cards where they are known to be:
+// they are taken out of the deck, and only the rest of every hand is dealt.
+// Every deal that fits is equally likely, and none is discarded (no rejection sampling).
+//
+// Returns an error if a known card is not left in the deck, or is known twice,
+// or a seat is known to hold more than 3 cards.
+func (d *Deck) DealKnown(seats int, muestra bool, known KnownCards) (Deal, error) {
+	if !slices.Contains(TABLE_SIZES, seats) {
+		return Deal{}, fmt.Errorf("Can't deal to %d seats", seats)
+	}
+	if len(known.Hands) > seats {
+		return Deal{}, fmt.Errorf("Known hands for %d seats, dealing to %d", len(known.Hands), seats)
+	}
+
+	for seat, hand := range known.Hands {
+		if len(hand) > 3 {
+			return Deal{}, fmt.Errorf("Seat %d is known to hold %d cards", seat+1, len(hand))
+		}
+	}
+
+	kCards := slices.Concat(known.Hands...)
+	if known.Muestra != NO_CARD {
+		if !muestra {
+			return Deal{}, fmt.Errorf("Known muestra, dealing without one")
+		}
+		kCards = append(kCards, known.Muestra)
+	}
+	for i, c := range kCards {
+		if !slices.Contains(d.cards, c) {
+			return Deal{}, fmt.Errorf("Card %s is not left in the deck", c.ToString())
+		}
+		if slices.Contains(kCards[:i], c) {
+			return Deal{}, fmt.Errorf("Card %s is known twice", c.ToString())
+		}
+	}
+
+	need := 3 * seats
+	if muestra {
+		need++
+	}
+	need -= len(kCards)
+	if need > len(d.cards)-len(kCards) {
+		return Deal{}, fmt.Errorf("Can't deal %d cards, %d left", need, len(d.cards)-len(kCards))
+	}
+	d.cards = CardsExcluding(d.cards, kCards)
+
+	deal := Deal{Hands: make([]Hand, seats), Muestra: known.Muestra}
+	for seat := range deal.Hands {
+		var hand []Card
+		if seat < len(known.Hands) {
+			hand = known.Hands[seat]
+		}
+		rest, _ := d.Draw(3 - len(hand))
+		deal.Hands[seat] = Hand(slices.Concat(hand, rest))
+	}
+	if muestra && deal.Muestra == NO_CARD {
+		turned, _ := d.Draw(1)
+		deal.Muestra = turned[0]
+	}
+	return deal, nil
+}
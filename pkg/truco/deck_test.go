@@ -0,0 +1,100 @@
+package truco
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDeck(t *testing.T) {
+	a, b := NewDeck(7), NewDeck(7)
+	for range 3 {
+		da, _ := a.Deal(4, true)
+		db, _ := b.Deal(4, true)
+		if !slices.EqualFunc(da.Hands, db.Hands, slices.Equal) || da.Muestra != db.Muestra {
+			t.Fatalf("expected the same deals with the same seed")
+		}
+		a.Reset()
+		b.Reset()
+	}
+
+	for _, seats := range TABLE_SIZES {
+		d := NewDeck(uint64(seats))
+		deal, err := d.Deal(seats, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		dealt := append(slices.Concat(toCards(deal.Hands)...), deal.Muestra)
+		if len(deal.Hands) != seats || len(CardsExcluding(dealt, ALL_CARDS)) != 0 || d.Len() != 40-len(dealt) {
+			t.Errorf("%d seats: unexpected deal %v", seats, deal)
+		}
+		for i, c := range dealt {
+			if slices.Contains(dealt[:i], c) {
+				t.Errorf("%d seats: card %s dealt twice", seats, c.ToString())
+			}
+		}
+		if rules, err := deal.Rules("UY"); err != nil || rules.Muestra() != deal.Muestra {
+			t.Errorf("expected uruguayan rules with the muestra dealt, got %v, %v", rules, err)
+		}
+	}
+
+	if _, err := NewDeck(1).Deal(3, false); err == nil {
+		t.Errorf("expected error dealing to 3 seats")
+	}
+	d := NewDeck(1)
+	_, _ = d.Deal(6, true)
+	_, _ = d.Deal(4, false)
+	if _, err := d.Deal(4, false); err == nil {
+		t.Errorf("expected error with a short deck")
+	}
+}
+
+func TestDealKnown(t *testing.T) {
+	mine := NewHand("1e 7e")
+	known := KnownCards{Hands: [][]Card{nil, mine}, Muestra: Card{N: 4, S: 'c'}}
+
+	d := NewDeck(3)
+	seen := make(map[Card]bool)
+	for range 50 {
+		d.Reset()
+		deal, err := d.DealKnown(4, true, known)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(deal.Hands[1][:2], mine) || deal.Muestra != known.Muestra {
+			t.Fatalf("expected the known cards where they are known, got %v", deal)
+		}
+		for seat, h := range deal.Hands {
+			if seat != 1 && (h.HasAll([]Card{mine[0]}) || h.HasAll([]Card{known.Muestra})) {
+				t.Fatalf("known card dealt to seat %d", seat+1)
+			}
+		}
+		seen[deal.Hands[1][2]] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("expected the rest of the hand dealt at random, got %d cards", len(seen))
+	}
+
+	for name, k := range map[string]KnownCards{
+		"twice":      {Hands: [][]Card{NewHand("1e"), NewHand("1e")}},
+		"four cards": {Hands: [][]Card{NewHand("1e 2e 3e 4e")}},
+		"no muestra": {Muestra: Card{N: 4, S: 'c'}},
+		"seats":      {Hands: make([][]Card, 3)},
+	} {
+		if _, err := NewDeck(3).DealKnown(2, false, k); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	d = NewDeck(3)
+	_, _ = d.Draw(40)
+	if _, err := d.DealKnown(2, false, KnownCards{Hands: [][]Card{NewHand("1e")}}); err == nil {
+		t.Errorf("expected error with a card not left in the deck")
+	}
+}
+
+func toCards(hands []Hand) [][]Card {
+	cards := make([][]Card, len(hands))
+	for i, h := range hands {
+		cards[i] = h
+	}
+	return cards
+}
@@ -1,6 +1,7 @@
 package truco
 
 import (
+	"slices"
 	"truco/pkg/math"
 )
 
@@ -114,6 +115,68 @@ func EnvidoHands(score uint8) (hands []Hand) {
 	return hands
 }
 
+// Gets all possible hands given a muestra and an envido (values as Hand.EnvidoUY).
+// No hand holds the muestra.
+func EnvidoHandsUY(score EnvidoConstraint, muestra Card) (hands []Hand) {
+	for cards := range math.Combinations(CardsExcluding(ALL_CARDS, []Card{muestra}), 3) {
+		h := Hand(cards)
+		if score.Allows(h.EnvidoUY(muestra)) {
+			hands = append(hands, h)
+		}
+	}
+	return hands
+}
+
+// Distribution of the envido a player could have
+type EnvidoHistogram struct {
+	Buckets []EnvidoBucket // only envidos the player can have, lowest first
+	Count   int            // amount of hands the player could have
+}
+
+// Single envido value of an EnvidoHistogram
+type EnvidoBucket struct {
+	Envido uint8   // envido as Hand.Envido, or Hand.EnvidoUY (200+ for flor)
+	Count  int     // amount of hands with this envido
+	P      float32 // probability of this envido
+}
+
+// EnvidoDistribution returns the distribution of the envido of a player, given:
+//   - score: what we know of the envido of the player
+//   - mCards: cards the player played
+//   - kCards: other known cards, the player doesn't hold them
+//   - m: muestra, NO_CARD for argentinian truco
+//
+// Every possible hand has the same chance.
+func EnvidoDistribution(score EnvidoConstraint, mCards, kCards []Card, m Card) EnvidoHistogram {
+	return EnvidoDistributionRules(RulesFor(m), score, mCards, kCards)
+}
+
+// EnvidoDistribution in the rules r, eg. of a tracked match
+func EnvidoDistributionRules(r Ruleset, score EnvidoConstraint, mCards, kCards []Card) EnvidoHistogram {
+	hands := rulesRange(r, score, mCards, kCards)
+
+	counts := make(map[uint8]int)
+	for _, h := range hands {
+		counts[r.Envido(h)]++
+	}
+
+	hist := EnvidoHistogram{
+		Buckets: make([]EnvidoBucket, 0, len(counts)),
+		Count:   len(hands),
+	}
+	for envido, count := range counts {
+		hist.Buckets = append(hist.Buckets, EnvidoBucket{
+			Envido: envido,
+			Count:  count,
+			P:      float32(count) / float32(len(hands)),
+		})
+	}
+	slices.SortFunc(hist.Buckets, func(a, b EnvidoBucket) int {
+		return int(a.Envido) - int(b.Envido)
+	})
+	return hist
+}
+
 // Probability a given envido is the highest of the table, given mCards and other kCards
 func PEnvidoHighest(mHand Hand, kCards []Card) float32 {
 	// mEnvido := mHand.Envido()
@@ -246,3 +246,96 @@ var envido33Check = []Hand{
 	{Card{6, 'e'}, Card{7, 'e'}, Card{1, 'b'}},
 	{Card{6, 'e'}, Card{7, 'e'}, Card{10, 'o'}},
 }
+
+func TestEnvidoDistribution(t *testing.T) {
+	mHand := NewHand("1e 7o 3c")
+
+	hist := EnvidoDistribution(EnvidoAny(), []Card{}, mHand, NO_CARD)
+	if hist.Count != 7770 {
+		t.Errorf("Expected every hand without mHand, got %d", hist.Count)
+	}
+	var p float32
+	var count int
+	for _, b := range hist.Buckets {
+		p += b.P
+		count += b.Count
+	}
+	if count != hist.Count || p < 0.9999 || p > 1.0001 {
+		t.Errorf("Expected buckets to add up, got %d hands and p=%f", count, p)
+	}
+
+	// son buenas with 27
+	for _, b := range EnvidoDistribution(EnvidoAtMost(27), []Card{}, mHand, NO_CARD).Buckets {
+		if b.Envido > 27 {
+			t.Errorf("Expected envido <= 27, got %d", b.Envido)
+		}
+	}
+
+	// played 7b: 7b 6b, 7e 6e or 7c 6c
+	hist = EnvidoDistribution(EnvidoExact(33), []Card{{7, 'b'}}, mHand, NO_CARD)
+	if len(hist.Buckets) != 1 || hist.Buckets[0].Envido != 33 || hist.Count != 37 {
+		t.Errorf("Expected 37 hands with 33, got %v", hist)
+	}
+}
+
+func TestEnvidoDistributionUY(t *testing.T) {
+	mHand := NewHand("1e 7o 3c")
+	m := Card{4, 'e'}
+
+	for _, b := range EnvidoDistribution(EnvidoDeclined(), []Card{}, mHand, m).Buckets {
+		if b.Envido > 200 {
+			t.Errorf("Expected no flor when not declared, got %d", b.Envido)
+		}
+	}
+
+	hist := EnvidoDistribution(EnvidoFlor(), []Card{}, mHand, m)
+	if hist.Count == 0 {
+		t.Errorf("Expected some hands with flor")
+	}
+	for _, b := range hist.Buckets {
+		if b.Envido <= 200 {
+			t.Errorf("Expected only flor, got %d", b.Envido)
+		}
+	}
+}
+
+func TestEnvidoHandsUY(t *testing.T) {
+	m := Card{4, 'c'}
+
+	// 2c (30) + 7 of another suit, and a third card that doesn't make flor
+	hands := EnvidoHandsUY(EnvidoExact(37), m)
+	if len(hands) == 0 {
+		t.Errorf("Expected some hands for envido 37")
+	}
+	for _, h := range hands {
+		if h.EnvidoUY(m) != 37 {
+			t.Errorf("Generated hand %v has envido %d, expected 37", h, h.EnvidoUY(m))
+		}
+		if slices.Contains(h, m) {
+			t.Errorf("Generated hand %v has the muestra", h)
+		}
+	}
+
+	// without piezas, as EnvidoHands: 6 and 7 of the same suit, third card of another suit.
+	//   - 6c 7c: any of the 30 cards of another suit
+	//   - other suits: 30 cards, but not the muestra (1c) nor the 5 piezas
+	m = Card{1, 'c'}
+	var count int
+	for _, h := range EnvidoHandsUY(EnvidoExact(33), m) {
+		if !slices.ContainsFunc(h.UY(m), func(c Card) bool { return c.S == 'p' }) {
+			count++
+		}
+	}
+	if count != 30+3*24 {
+		t.Errorf("Expected 102 hands without piezas for envido 33, got %d", count)
+	}
+
+	flor := EnvidoHandsUY(EnvidoFlor(), m)
+	count = 0
+	for v := uint8(220); v <= 247; v++ {
+		count += len(EnvidoHandsUY(EnvidoExact(v), m))
+	}
+	if count != len(flor) {
+		t.Errorf("Expected every flor value to add up to %d hands, got %d", len(flor), count)
+	}
+}
@@ -0,0 +1,115 @@
+package truco
+
+import (
+	"slices"
+	"truco/pkg/math"
+)
+
+// A range: hands a player could hold, each with its weight
+// (how likely the player is to hold it, or to play it the way they did)
+type Range []WeightedHand
+
+// Creates a range from a list of hands (eg. from CardRange or EnvidoHands), with weight 1 each.
+//
+//   - hands holding any of kCards are removed: these cards are known elsewhere
+//   - repeated hands (in any order) are only counted once
+func NewRange(hands []Hand, kCards []Card) Range {
+	r := make(Range, 0, len(hands))
+	seen := make(map[[3]Card]bool)
+	for _, h := range hands {
+		if len(CardsExcluding(h, kCards)) != len(h) {
+			continue
+		}
+		key := rangeKey(h)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		r = append(r, WeightedHand{Hand: h, Weight: 1})
+	}
+	return r
+}
+
+// Cards of the hand sorted as in ALL_CARDS, to compare hands in any order
+func rangeKey(h Hand) [3]Card {
+	key := [3]Card(h)
+	slices.SortFunc(key[:], func(a, b Card) int {
+		return cardIndex(a) - cardIndex(b)
+	})
+	return key
+}
+
+// Result of RangeEquity, seen by mRange
+type EquityMatrix struct {
+	MRange Range
+	ORange Range
+	Truco  [][]float32 // Truco[i][j]: % of plays MRange[i] wins against ORange[j], -1 if hands share cards
+	Envido [][]float32 // Envido[i][j]: 1 if MRange[i] wins envido against ORange[j], 0 if loses, -1 if hands share cards
+
+	TrucoEquity  float32 // weighted average of Truco, over all pairs of hands that can be dealt together
+	EnvidoEquity float32 // weighted average of Envido, over all pairs of hands that can be dealt together
+	Weight       float64 // total weight of the pairs that can be dealt together
+}
+
+// RangeEquity plays every hand of mRange against every hand of oRange, given:
+//   - m: muestra, NO_CARD for argentinian truco
+//   - isMHandFirst: mRange plays first (wins envido ties)
+//
+// Truco is played as TrucoStrength: every permutation against every permutation.
+// Pairs of hands that share a card can't be dealt together: they don't count in the equity.
+func RangeEquity(mRange, oRange Range, m Card, isMHandFirst bool) EquityMatrix {
+	return RangeEquityRules(RulesFor(m), mRange, oRange, isMHandFirst)
+}
+
+// RangeEquity, for the variant of rules r
+func RangeEquityRules(r Ruleset, mRange, oRange Range, isMHandFirst bool) EquityMatrix {
+	eq := EquityMatrix{
+		MRange: mRange,
+		ORange: oRange,
+		Truco:  make([][]float32, len(mRange)),
+		Envido: make([][]float32, len(mRange)),
+	}
+
+	oPerms := make([][][]Card, len(oRange))
+	oEnvidos := make([]uint8, len(oRange))
+	for j, o := range oRange {
+		oPerms[j] = math.PermutationsRaw(o.Hand, 3)
+		oEnvidos[j] = r.Envido(o.Hand)
+	}
+
+	var trucoSum, envidoSum float64
+	for i, mw := range mRange {
+		mPerms := math.PermutationsRaw(mw.Hand, 3)
+		mEnvido := r.Envido(mw.Hand)
+		eq.Truco[i] = make([]float32, len(oRange))
+		eq.Envido[i] = make([]float32, len(oRange))
+
+		for j, ow := range oRange {
+			if len(CardsExcluding(ow.Hand, mw.Hand)) != len(ow.Hand) {
+				eq.Truco[i][j] = -1
+				eq.Envido[i][j] = -1
+				continue
+			}
+
+			var wins int
+			for _, mPerm := range mPerms {
+				for _, oPerm := range oPerms[j] {
+					wins += r.Beats(mPerm, oPerm)
+				}
+			}
+			eq.Truco[i][j] = float32(wins) / float32(len(mPerms)*len(oPerms[j]))
+			eq.Envido[i][j] = float32(EnvidoBeats(mEnvido, oEnvidos[j], isMHandFirst))
+
+			w := mw.Weight * ow.Weight
+			trucoSum += w * float64(eq.Truco[i][j])
+			envidoSum += w * float64(eq.Envido[i][j])
+			eq.Weight += w
+		}
+	}
+
+	if eq.Weight > 0 {
+		eq.TrucoEquity = float32(trucoSum / eq.Weight)
+		eq.EnvidoEquity = float32(envidoSum / eq.Weight)
+	}
+	return eq
+}
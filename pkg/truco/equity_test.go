@@ -0,0 +1,70 @@
+package truco
+
+import (
+	"testing"
+)
+
+func TestNewRange(t *testing.T) {
+	hands := []Hand{NewHand("1e 7e 3c"), NewHand("3c 1e 7e"), NewHand("4b 5b 6b")}
+	r := NewRange(hands, []Card{})
+	if len(r) != 2 {
+		t.Errorf("Expected repeated hands to be removed, got %d hands", len(r))
+	}
+
+	r = NewRange(hands, []Card{{5, 'b'}})
+	if len(r) != 1 {
+		t.Errorf("Expected hands with known cards to be removed, got %d hands", len(r))
+	}
+
+	for _, h := range NewRange(EnvidoHands(33), []Card{}) {
+		if h.Hand.Envido() != 33 {
+			t.Errorf("Expected only hands with 33, got %v", h.Hand)
+		}
+	}
+}
+
+// A single hand against every other hand is its TrucoStrength
+func TestRangeEquityStrength(t *testing.T) {
+	mHand := NewHand("7e 12c 2o")
+	mRange := NewRange([]Hand{mHand}, []Card{})
+	oRange := NewRange(CardRange(EnvidoAny(), []Card{}, mHand), []Card{})
+
+	eq := RangeEquity(mRange, oRange, NO_CARD, true)
+	if diff := eq.TrucoEquity - mHand.TrucoStrength(); diff > 1e-5 || diff < -1e-5 {
+		t.Errorf("Expected equity %f, got %f", mHand.TrucoStrength(), eq.TrucoEquity)
+	}
+	if eq.Weight != 7770 {
+		t.Errorf("Expected 7770 pairs, got %f", eq.Weight)
+	}
+}
+
+func TestRangeEquity(t *testing.T) {
+	mRange := NewRange(EnvidoHands(33), []Card{})
+	oRange := NewRange(EnvidoHands(33), []Card{})
+
+	eq := RangeEquity(mRange, oRange, NO_CARD, true)
+	if eq.EnvidoEquity != 1 {
+		t.Errorf("Expected to win every 33 vs 33 playing first, got %f", eq.EnvidoEquity)
+	}
+	eq = RangeEquity(mRange, oRange, NO_CARD, false)
+	if eq.EnvidoEquity != 0 {
+		t.Errorf("Expected to lose every 33 vs 33 playing second, got %f", eq.EnvidoEquity)
+	}
+
+	// same hand can't be dealt to both players
+	for i := range mRange {
+		if eq.Truco[i][i] != -1 || eq.Envido[i][i] != -1 {
+			t.Errorf("Expected %v vs itself to be blocked", mRange[i].Hand)
+		}
+	}
+
+	// weights
+	mRange = NewRange([]Hand{NewHand("1e 1b 7e"), NewHand("4e 4b 5c")}, []Card{})
+	oRange = NewRange([]Hand{NewHand("3e 3b 3o")}, []Card{})
+	mRange[0].Weight = 3
+	eq = RangeEquity(mRange, oRange, NO_CARD, true)
+	expected := (3*eq.Truco[0][0] + eq.Truco[1][0]) / 4
+	if eq.TrucoEquity != expected {
+		t.Errorf("Expected weighted equity %f, got %f", expected, eq.TrucoEquity)
+	}
+}
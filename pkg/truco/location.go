@@ -0,0 +1,124 @@
+package truco
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// What the table knows of the hand of a seat
+type SeatView struct {
+	Played []Card           // cards the seat played
+	Hand   Hand             // full hand if known (eg. my own hand), nil otherwise
+	Envido EnvidoConstraint // what the seat declared of its envido
+}
+
+// Result of CardLocations
+type CardLocation struct {
+	Cards   []Card      // every card, as ALL_CARDS
+	P       [][]float32 // P[i][seat]: chance that seat holds Cards[i]
+	Samples int         // deals sampled
+}
+
+// CardLocations computes, for every card, the chance that each seat holds it, given:
+//   - seats: what is known of each seat
+//   - m: muestra, NO_CARD for argentinian truco
+//   - samples: deals to sample
+//   - seed: seed of the dealer, same seed gives same result
+//
+// Each deal gives every seat a hand that fits what it showed, and no card is dealt twice.
+// Deals are drawn from the range of each seat, and discarded when two seats share a card.
+//
+// Returns an error if no deal fits what the seats showed.
+func CardLocations(seats []SeatView, m Card, samples int, seed uint64) (CardLocation, error) {
+	return CardLocationsRules(RulesFor(m), seats, samples, seed)
+}
+
+// CardLocations in the rules r: the card turned up (muestra, vira) is on the table,
+// and cards out of the deck of r are held by no seat
+func CardLocationsRules(r Ruleset, seats []SeatView, samples int, seed uint64) (CardLocation, error) {
+	loc := CardLocation{Cards: ALL_CARDS, P: make([][]float32, len(ALL_CARDS))}
+	for i := range loc.P {
+		loc.P[i] = make([]float32, len(seats))
+	}
+	if samples < 1 {
+		return loc, fmt.Errorf("Need at least one sample")
+	}
+
+	// cards known to belong to a seat, or to the table
+	known := make(map[Card]int)
+	if m := r.Muestra(); m != NO_CARD {
+		known[m] = -1
+	}
+	for s, seat := range seats {
+		for _, c := range slices.Concat(seat.Played, seat.Hand) {
+			if owner, ok := known[c]; ok && owner != s {
+				return loc, fmt.Errorf("Card %s is held twice", c.ToString())
+			}
+			known[c] = s
+		}
+	}
+
+	ranges := make([][]Hand, len(seats))
+	for s, seat := range seats {
+		if len(seat.Hand) == 3 {
+			ranges[s] = []Hand{seat.Hand}
+			continue
+		}
+		kCards := make([]Card, 0, len(known))
+		for c, owner := range known {
+			if owner != s {
+				kCards = append(kCards, c)
+			}
+		}
+		ranges[s] = rulesRange(r, seat.Envido, seat.Played, kCards)
+		if len(ranges[s]) == 0 {
+			return loc, fmt.Errorf("No hand fits what seat %d showed", s+1)
+		}
+	}
+
+	rng := rand.New(rand.NewPCG(seed, seed))
+	counts := make(map[Card][]int, len(ALL_CARDS))
+	for _, c := range ALL_CARDS {
+		counts[c] = make([]int, len(seats))
+	}
+
+	// every sample may be discarded: give up after many tries
+	for try := 0; loc.Samples < samples && try < samples*100; try++ {
+		dealt := make(map[Card]bool, 3*len(seats))
+		hands := make([]Hand, len(seats))
+		ok := true
+		for s := range seats {
+			hands[s] = ranges[s][rng.IntN(len(ranges[s]))]
+			for _, c := range hands[s] {
+				if dealt[c] {
+					ok = false
+				}
+				dealt[c] = true
+			}
+			if !ok {
+				break
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		loc.Samples++
+		for s, h := range hands {
+			for _, c := range h {
+				counts[c][s]++
+			}
+		}
+	}
+	if loc.Samples == 0 {
+		return loc, fmt.Errorf("No deal fits what the seats showed")
+	}
+
+	for i, c := range loc.Cards {
+		for s := range seats {
+			loc.P[i][s] = float32(counts[c][s]) / float32(loc.Samples)
+		}
+	}
+	return loc, nil
+}
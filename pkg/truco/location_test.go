@@ -0,0 +1,65 @@
+package truco
+
+import (
+	gomath "math"
+	"slices"
+	"testing"
+)
+
+func TestCardLocations(t *testing.T) {
+	mHand := NewHand("1e 7o 3c")
+	seats := []SeatView{
+		{Hand: mHand},
+		{Played: []Card{{1, 'b'}}},
+		{Envido: EnvidoExact(33)},
+		{},
+	}
+
+	loc, err := CardLocations(seats, NO_CARD, 2000, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if loc.Samples != 2000 {
+		t.Errorf("Expected 2000 samples, got %d", loc.Samples)
+	}
+
+	sums := make([]float64, len(seats))
+	for i, c := range loc.Cards {
+		for s := range seats {
+			sums[s] += float64(loc.P[i][s])
+		}
+		if slices.Contains(mHand, c) && loc.P[i][0] != 1 {
+			t.Errorf("Expected my card %s to be mine, got %f", c.ToString(), loc.P[i][0])
+		}
+		if c == (Card{1, 'b'}) && loc.P[i][1] != 1 {
+			t.Errorf("Expected the played 1b to belong to seat 2, got %f", loc.P[i][1])
+		}
+		if c == (Card{7, 'e'}) && loc.P[i][2] < 0.3 {
+			t.Errorf("Expected 33 of envido to hold 7e often, got %f", loc.P[i][2])
+		}
+		if c == (Card{7, 'o'}) && loc.P[i][2] != 0 {
+			t.Errorf("Expected no other seat to hold my 7o, got %f", loc.P[i][2])
+		}
+	}
+	for s := range seats {
+		if gomath.Abs(sums[s]-3) > 1e-3 {
+			t.Errorf("Expected seat %d to hold 3 cards, got %f", s, sums[s])
+		}
+	}
+
+	// seat 4 knows nothing: every unseen card with about the same chance
+	for i, c := range loc.Cards {
+		if c.N == 4 && (loc.P[i][3] < 0.04 || loc.P[i][3] > 0.14) {
+			t.Errorf("Expected %s at seat 4 near %f, got %f", c.ToString(), 3.0/34, loc.P[i][3])
+		}
+	}
+
+	seats[2] = SeatView{Hand: NewHand("1b 4e 4o")}
+	if _, err := CardLocations(seats, NO_CARD, 100, 1); err == nil {
+		t.Errorf("Expected error when a card is held twice")
+	}
+	seats[2] = SeatView{Played: []Card{{7, 'b'}, {6, 'b'}}, Envido: EnvidoExact(20)}
+	if _, err := CardLocations(seats, NO_CARD, 100, 1); err == nil {
+		t.Errorf("Expected error when no hand fits the envido")
+	}
+}
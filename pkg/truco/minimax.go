@@ -0,0 +1,189 @@
+package truco
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Exact solver for the card play of a single hand, when both players
+// know each other's cards (perfect information).
+//
+// Rules of the trick:
+//   - the leader plays a card, the other player answers
+//   - higher truco rank wins the trick, and its player leads the next one
+//   - on a tie (parda) the same player leads again
+//   - the hand is decided as in TrucoBeats
+//
+// Bets (truco, envido) are not part of the search: it only solves who takes the hand.
+
+// Result of Minimax
+type SolveResult struct {
+	Value int    // 1 if mHand takes the hand with best play from both sides, 0 if tie or loss (as TrucoBeats)
+	Line  []Card // principal line: cards still to be played, in the order they hit the table
+}
+
+// Minimax finds the optimal play for both players, given:
+//   - mHand, oHand: full hands of both players (including cards already played)
+//   - m: muestra, NO_CARD for argentinian truco
+//   - isMHandFirst: mHand leads the first trick
+//   - mPlayed, oPlayed: cards already played by each player, in the order played
+//
+// mHand maximizes the value, oHand minimizes it.
+// Among cards that are equally good, the lowest one is played.
+//
+// Returns an error if the cards played are not consistent with the hands
+// or with the order of play.
+func Minimax(mHand, oHand Hand, m Card, isMHandFirst bool, mPlayed, oPlayed []Card) (SolveResult, error) {
+	return solver{r: RulesFor(m)}.minimax(mHand, oHand, isMHandFirst, mPlayed, oPlayed, nil)
+}
+
+// Minimax with the rules of the solver.
+// results are the results of the first tricks played (seen by mHand: 1, -1, 0):
+// the tricks without a result are taken from the cards played.
+func (s solver) minimax(mHand, oHand Hand, isMHandFirst bool, mPlayed, oPlayed []Card, results []int) (SolveResult, error) {
+	if !mHand.HasAll(mPlayed) || !oHand.HasAll(oPlayed) {
+		return SolveResult{}, fmt.Errorf("Played cards must belong to the hands")
+	}
+
+	mLeads := isMHandFirst
+	tricks := min(len(mPlayed), len(oPlayed))
+	results = slices.Clone(results[:min(len(results), tricks)])
+	for i := len(results); i < tricks; i++ {
+		results = append(results, s.trick(mPlayed[i], oPlayed[i]))
+	}
+	for _, res := range results {
+		mLeads = s.nextLeads(mLeads, res)
+	}
+
+	table := NO_CARD
+	if len(mPlayed) > tricks+1 || len(oPlayed) > tricks+1 {
+		return SolveResult{}, fmt.Errorf("Players can't be more than one card apart")
+	} else if len(mPlayed) > tricks {
+		if !mLeads {
+			return SolveResult{}, fmt.Errorf("mHand can't lead this trick")
+		}
+		table = mPlayed[tricks]
+	} else if len(oPlayed) > tricks {
+		if mLeads {
+			return SolveResult{}, fmt.Errorf("oHand can't lead this trick")
+		}
+		table = oPlayed[tricks]
+	}
+
+	mRem := CardsExcluding(mHand, mPlayed)
+	oRem := CardsExcluding(oHand, oPlayed)
+	value, line := s.solve(mRem, oRem, results, mLeads, table)
+	return SolveResult{Value: value, Line: line}, nil
+}
+
+type solver struct {
+	r     Ruleset // rules of the variant played
+	fixed bool    // every trick is led by the leader of the first one, as in the tracker (see PlayView)
+}
+
+// Returns if mHand leads the next trick, after a trick with result res
+func (s solver) nextLeads(mLeads bool, res int) bool {
+	if s.fixed || res == 0 {
+		return mLeads
+	}
+	return res == 1
+}
+
+// Result of a single trick, seen by mHand: 1 win, -1 loss, 0 tie
+func (s solver) trick(mCard, oCard Card) int {
+	mRank, oRank := s.r.Rank(mCard), s.r.Rank(oCard)
+	if mRank > oRank {
+		return 1
+	} else if mRank < oRank {
+		return -1
+	}
+	return 0
+}
+
+// Searches the rest of the hand.
+// The leader is to play if table == NO_CARD, else the other player answers the card on the table.
+func (s solver) solve(mRem, oRem []Card, results []int, mLeads bool, table Card) (int, []Card) {
+	if res, done := trucoResult(results); done {
+		return resultValue(res), []Card{}
+	}
+
+	isMTurn := mLeads == (table == NO_CARD)
+	cards := oRem
+	if isMTurn {
+		cards = mRem
+	}
+	// lowest cards first: on equal value we keep the cheapest card
+	cards = slices.Clone(cards)
+	slices.SortStableFunc(cards, func(a, b Card) int {
+		return int(s.r.Rank(a)) - int(s.r.Rank(b))
+	})
+
+	bestValue := -1
+	var bestLine []Card
+	for _, c := range cards {
+		var value int
+		var line []Card
+
+		nextM, nextO := mRem, oRem
+		if isMTurn {
+			nextM = CardsExcluding(mRem, []Card{c})
+		} else {
+			nextO = CardsExcluding(oRem, []Card{c})
+		}
+
+		if table == NO_CARD {
+			value, line = s.solve(nextM, nextO, results, mLeads, c)
+		} else {
+			var res int
+			if isMTurn {
+				res = s.trick(c, table)
+			} else {
+				res = s.trick(table, c)
+			}
+			value, line = s.solve(nextM, nextO, append(slices.Clone(results), res), s.nextLeads(mLeads, res), NO_CARD)
+		}
+
+		if bestValue == -1 || (isMTurn && value > bestValue) || (!isMTurn && value < bestValue) {
+			bestValue = value
+			bestLine = append([]Card{c}, line...)
+		}
+	}
+	return bestValue, bestLine
+}
+
+// Result of the hand given the results of the tricks played so far (seen by mHand: 1, -1, 0).
+// Same rules as TrucoBeats, but a hand can be decided before the third trick.
+//
+// Returns done=false if the hand is still undecided.
+func trucoResult(results []int) (res int, done bool) {
+	if len(results) < 2 {
+		return 0, false
+	}
+	s0, s1 := results[0], results[1]
+
+	if s0 == 0 && s1 != 0 {
+		// tie in the first round is defined inmediately after
+		return s1, true
+	} else if s0 != 0 && (s1 == 0 || s0 == s1) {
+		// tie defined by winner of first round, or a player won first two rounds
+		return s0, true
+	} else if len(results) < 3 {
+		return 0, false
+	}
+
+	s2 := results[2]
+	if s0 == 0 || s2 != 0 {
+		// after two ties, or alternate winners, last round defines
+		return s2, true
+	}
+	// alternate winners and tie in last round: defined by winner of first round
+	return s0, true
+}
+
+// Value of a hand result for mHand, as in TrucoBeats: 1 win, 0 tie or loss
+func resultValue(res int) int {
+	if res == 1 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package truco
+
+import (
+	"slices"
+	"testing"
+	"truco/pkg/math"
+)
+
+func TestTrucoResult(t *testing.T) {
+	tests := []struct {
+		results []int
+		res     int
+		done    bool
+	}{
+		{[]int{}, 0, false},
+		{[]int{1}, 0, false},
+		{[]int{1, 1}, 1, true},
+		{[]int{-1, 0}, -1, true},
+		{[]int{0, 1}, 1, true},
+		{[]int{0, 0}, 0, false},
+		{[]int{1, -1}, 0, false},
+		{[]int{1, -1, 0}, 1, true},
+		{[]int{1, -1, -1}, -1, true},
+		{[]int{0, 0, 0}, 0, true},
+	}
+
+	for _, tt := range tests {
+		res, done := trucoResult(tt.results)
+		if res != tt.res || done != tt.done {
+			t.Errorf("trucoResult(%v) = %d, %v; want %d, %v", tt.results, res, done, tt.res, tt.done)
+		}
+	}
+}
+
+func TestMinimax(t *testing.T) {
+	// 1e wins the first trick, but both 4s lose to any 3
+	res, err := Minimax(NewHand("1e 4c 4o"), NewHand("3e 3b 3o"), NO_CARD, true, []Card{}, []Card{})
+	if err != nil || res.Value != 0 {
+		t.Errorf("Expected loss, got %v (%v)", res, err)
+	}
+
+	// any order wins: the cheapest card is played first
+	res, _ = Minimax(NewHand("1e 1b 4c"), NewHand("3e 3b 3o"), NO_CARD, true, []Card{}, []Card{})
+	if res.Value != 1 || res.Line[0] != (Card{4, 'c'}) {
+		t.Errorf("Expected win leading 4c, got %v", res)
+	}
+
+	// after seeing 2o, oHand must win the first trick
+	res, _ = Minimax(NewHand("2o 7e 4c"), NewHand("3e 5b 6o"), NO_CARD, true, []Card{{2, 'o'}}, []Card{})
+	if res.Value != 0 || res.Line[0] != (Card{3, 'e'}) {
+		t.Errorf("Expected 3e to answer 2o, got %v", res.Line)
+	}
+
+	// piezas
+	res, _ = Minimax(NewHand("2e 4c 4o"), NewHand("1e 1b 3c"), Card{1, 'e'}, false, []Card{}, []Card{})
+	if res.Value != 0 {
+		t.Errorf("Expected loss against 1b 3c, got %v", res)
+	}
+	res, _ = Minimax(NewHand("2e 4e 4o"), NewHand("1e 1b 3c"), Card{5, 'e'}, false, []Card{}, []Card{})
+	if res.Value != 1 {
+		t.Errorf("Expected win with two piezas, got %v", res)
+	}
+}
+
+func TestMinimaxErrors(t *testing.T) {
+	mHand, oHand := NewHand("1e 4c 4o"), NewHand("3e 3b 3o")
+
+	if _, err := Minimax(mHand, oHand, NO_CARD, true, []Card{{7, 'e'}}, []Card{}); err == nil {
+		t.Errorf("Expected error playing a card out of the hand")
+	}
+	if _, err := Minimax(mHand, oHand, NO_CARD, false, []Card{{4, 'c'}}, []Card{}); err == nil {
+		t.Errorf("Expected error leading out of turn")
+	}
+	if _, err := Minimax(mHand, oHand, NO_CARD, true, []Card{{4, 'c'}, {4, 'o'}}, []Card{}); err == nil {
+		t.Errorf("Expected error playing two cards in a row")
+	}
+}
+
+// The principal line, played as fixed orders, must give the value of the game
+func TestMinimaxLine(t *testing.T) {
+	mHands := []Hand{NewHand("1e 7o 3c"), NewHand("4e 4b 5c"), NewHand("12o 1c 2e"), NewHand("7e 6e 3o")}
+	for _, mHand := range mHands {
+		for oH := range math.Combinations(CardsExcluding(ALL_CARDS, mHand), 3) {
+			oHand := Hand(oH)
+			for _, isMHandFirst := range []bool{true, false} {
+				res, err := Minimax(mHand, oHand, NO_CARD, isMHandFirst, []Card{}, []Card{})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				mOrder := make(Hand, 0, 3)
+				oOrder := make(Hand, 0, 3)
+				for _, c := range res.Line {
+					if slices.Contains(mHand, c) {
+						mOrder = append(mOrder, c)
+					} else {
+						oOrder = append(oOrder, c)
+					}
+				}
+				// the hand may end early: the rest of the cards don't change the result
+				mOrder = append(mOrder, CardsExcluding(mHand, mOrder)...)
+				oOrder = append(oOrder, CardsExcluding(oHand, oOrder)...)
+
+				if TrucoBeats(mOrder, oOrder, NO_CARD) != res.Value {
+					t.Fatalf("%v vs %v: line %v does not give value %d", mHand, oHand, res.Line, res.Value)
+				}
+			}
+		}
+	}
+}
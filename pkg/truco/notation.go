@@ -0,0 +1,157 @@
+package truco
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"truco/pkg/math"
+)
+
+// Range notation, using the ranks of the matrix (Card.ToRank, see RANKS).
+//
+// A range is a list of terms separated by commas, eg. "1e 3+, 7e 2 env, 3 3 x:0.5"
+//
+// Each term is 2 or 3 cards, in any order:
+//   - rank: a card of that rank, eg. "1e", "3", "7f"
+//   - rank+: a card of that rank or higher, eg. "3+" is any 3, 1f, 7o, 7e, 1b, 1e
+//   - x: any card
+//
+// With 2 cards, they are the 2 highest cards of the hand (as the pairs of the matrix).
+// With 3 cards, the whole hand.
+//
+// After the cards, optionally:
+//   - env: hand has envido (2 cards of the same suit, as the matrix)
+//   - noenv: hand has no envido
+//   - :weight: weight of the hands of this term, default 1
+//
+// A hand matched by several terms keeps the highest weight.
+
+// A parsed card of a range term
+type rangeCard struct {
+	rank   string // rank of RANKS, "" matches any card
+	orMore bool   // matches the rank or higher
+}
+
+// A parsed term of a range
+type rangeTerm struct {
+	cards  []rangeCard
+	envido int8 // 1: only hands with envido, -1: only hands without, 0: any
+	weight float64
+}
+
+// ParseRange parses a range in range notation, and expands it to concrete hands.
+// Hands holding any of kCards are left out: these cards are known elsewhere.
+//
+// Returns an error if the notation is not valid.
+func ParseRange(notation string, kCards []Card) (Range, error) {
+	terms := make([]rangeTerm, 0)
+	for _, s := range strings.Split(notation, ",") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		term, err := parseRangeTerm(s)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, term)
+	}
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("Empty range")
+	}
+
+	r := make(Range, 0)
+	for h := range math.Combinations(CardsExcluding(ALL_CARDS, kCards), 3) {
+		hand := Hand(h)
+		var weight float64
+		for _, term := range terms {
+			if term.matches(hand) {
+				weight = max(weight, term.weight)
+			}
+		}
+		if weight > 0 {
+			r = append(r, WeightedHand{Hand: hand, Weight: weight})
+		}
+	}
+	return r, nil
+}
+
+func parseRangeTerm(s string) (rangeTerm, error) {
+	term := rangeTerm{weight: 1}
+
+	if i := strings.LastIndex(s, ":"); i != -1 {
+		weight, err := strconv.ParseFloat(strings.TrimSpace(s[i+1:]), 64)
+		if err != nil || weight < 0 {
+			return term, fmt.Errorf("Invalid weight in '%s'", strings.TrimSpace(s))
+		}
+		term.weight = weight
+		s = s[:i]
+	}
+
+	for _, tok := range strings.Fields(s) {
+		switch {
+		case tok == "env":
+			term.envido = 1
+		case tok == "noenv":
+			term.envido = -1
+		case tok == "x":
+			term.cards = append(term.cards, rangeCard{})
+		default:
+			card := rangeCard{rank: strings.TrimSuffix(tok, "+"), orMore: strings.HasSuffix(tok, "+")}
+			// piezas (rank > 14) only exist in uruguayan truco
+			if rank, ok := RANKS[card.rank]; !ok || rank > 14 {
+				return term, fmt.Errorf("Unknown rank '%s'", tok)
+			}
+			term.cards = append(term.cards, card)
+		}
+	}
+
+	if len(term.cards) < 2 || len(term.cards) > 3 {
+		return term, fmt.Errorf("Expected 2 or 3 cards in '%s'", strings.TrimSpace(s))
+	}
+	return term, nil
+}
+
+func (rc rangeCard) matches(c Card) bool {
+	if rc.rank == "" {
+		return true
+	}
+	if rc.orMore {
+		return c.Truco() >= RANKS[rc.rank]
+	}
+	return c.ToRank() == rc.rank
+}
+
+func (t rangeTerm) matches(hand Hand) bool {
+	if t.envido != 0 {
+		hasEnvido := slices.Clone(hand).Envido() >= 20
+		if hasEnvido != (t.envido == 1) {
+			return false
+		}
+	}
+
+	cards := slices.Clone(hand)
+	slices.SortStableFunc(cards, SortForTruco)
+	if len(t.cards) == 2 {
+		// the 2 highest cards: same truco value is same rank, ties don't matter
+		cards = cards[:2]
+	}
+	return t.matchesAny(cards)
+}
+
+// Returns true if the cards of the term can be assigned to cards, in some order
+func (t rangeTerm) matchesAny(cards []Card) bool {
+	for _, p := range math.PermutationsRaw(cards, len(cards)) {
+		ok := true
+		for i := range t.cards {
+			if !t.cards[i].matches(p[i]) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package truco
+
+import (
+	"testing"
+)
+
+func TestParseRange(t *testing.T) {
+	tests := []struct {
+		notation string
+		count    int
+	}{
+		{"1e 1b", 38},                       // third card is any other
+		{"1b 1e", 38},                       // any order
+		{"3 3 3", 4},                        //
+		{"3 3", 6*32 + 4},                   // 32 cards lower than a 3, or a third 3
+		{"3 3 x", 6*36 + 4},                 // any third card
+		{"1e 3+", 741 - 496},                // 1e with any 2 cards, but not 2 cards lower than 3
+		{"7e 2 env", 28 + 13 + 13 + 14 + 3}, // 2e + any lower card, other 2s + lower card of espada or same suit, 2 2s with 2e
+		{"1e 1b, 3 3 3", 38 + 4},            // union
+		{"1e 1b:0.5, 1e x x", 741},          //
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRange(tt.notation, []Card{})
+		if err != nil {
+			t.Errorf("ParseRange(%s): unexpected error %v", tt.notation, err)
+			continue
+		}
+		if len(r) != tt.count {
+			t.Errorf("ParseRange(%s): expected %d hands, got %d", tt.notation, tt.count, len(r))
+		}
+	}
+
+	// known cards are left out
+	r, _ := ParseRange("1e 1b", []Card{{3, 'o'}})
+	if len(r) != 37 {
+		t.Errorf("Expected 37 hands without 3o, got %d", len(r))
+	}
+}
+
+func TestParseRangeWeights(t *testing.T) {
+	r, err := ParseRange("1e 1b:0.5, 1e 1b 3", []Card{})
+	if err != nil {
+		t.Fatalf("Unexpected error %v", err)
+	}
+	for _, h := range r {
+		isThree := h.Hand[0].N == 3 || h.Hand[1].N == 3 || h.Hand[2].N == 3
+		if isThree && h.Weight != 1 {
+			t.Errorf("Expected weight 1 for %v, got %f", h.Hand, h.Weight)
+		} else if !isThree && h.Weight != 0.5 {
+			t.Errorf("Expected weight 0.5 for %v, got %f", h.Hand, h.Weight)
+		}
+	}
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	for _, notation := range []string{"", "1e", "1e 1b 3 3", "1e 9", "4p 1e", "1e 1b:abc", "1e 1b:-1"} {
+		if _, err := ParseRange(notation, []Card{}); err == nil {
+			t.Errorf("ParseRange(%s): expected error", notation)
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package truco
+
+import (
+	"slices"
+	"truco/pkg/math"
+)
+
+// Chances of being dealt a hand, or a feature of a hand, for argentinian truco.
+//
+// Every count is computed from the amount of unseen cards of each rank and suit,
+// with combinatorics: no hand is enumerated.
+
+// Cards that beat any other card but the other bravas
+var BRAVAS = []Card{{1, 'e'}, {1, 'b'}, {7, 'e'}, {7, 'o'}}
+
+// Unseen cards, counted by suit and truco rank
+type DealOdds struct {
+	count  int
+	bySuit [4][15]int // bySuit[suit][truco rank]: 1 if the card is unseen
+}
+
+// Creates the odds of being dealt 3 cards from the cards not in kCards
+func NewDealOdds(kCards []Card) DealOdds {
+	var d DealOdds
+	for _, c := range CardsExcluding(ALL_CARDS, kCards) {
+		d.bySuit[suitIndex(c.S)][c.Truco()]++
+		d.count++
+	}
+	return d
+}
+
+func suitIndex(s uint8) int {
+	switch s {
+	case 'e':
+		return 0
+	case 'b':
+		return 1
+	case 'o':
+		return 2
+	}
+	return 3
+}
+
+// Amount of hands that can be dealt
+func (d DealOdds) Hands() int {
+	return int(math.PickC(d.count, 3))
+}
+
+// Chance of being dealt exactly this hand
+func (d DealOdds) Hand(h Hand) float64 {
+	for _, c := range h {
+		if d.bySuit[suitIndex(c.S)][c.Truco()] == 0 {
+			return 0
+		}
+	}
+	return d.p(1)
+}
+
+// Chance of being dealt at least one of cards
+func (d DealOdds) AnyOf(cards []Card) float64 {
+	var unseen int
+	for _, c := range cards {
+		unseen += d.bySuit[suitIndex(c.S)][c.Truco()]
+	}
+	return 1 - d.p(int(math.PickC(d.count-unseen, 3)))
+}
+
+// Chance of being dealt at least one brava
+func (d DealOdds) Brava() float64 {
+	return d.AnyOf(BRAVAS)
+}
+
+// Chance of being dealt flor: 3 cards of the same suit
+func (d DealOdds) Flor() float64 {
+	var count int
+	for _, n := range d.suitCounts(0, 14) {
+		count += int(math.PickC(n, 3))
+	}
+	return d.p(count)
+}
+
+// Chance of being dealt envido: at least 2 cards of the same suit
+func (d DealOdds) Envido() float64 {
+	return 1 - d.p(distinctSuits(d.suitCounts(0, 14)))
+}
+
+// Chance of being dealt a pair of the matrix: the 2 highest cards of the hand are of ranks r0 and r1 (see RANKS),
+// with envido or without it.
+func (d DealOdds) Pair(r0, r1 string, isEnvido bool) float64 {
+	return d.p(d.PairCount(r0, r1, isEnvido))
+}
+
+// Amount of hands of a pair of the matrix, as in Pair
+func (d DealOdds) PairCount(r0, r1 string, isEnvido bool) int {
+	a, b := RANKS[r0], RANKS[r1]
+	// piezas (rank > 14) only exist in uruguayan truco
+	if a == 0 || b == 0 || a > 14 || b > 14 {
+		return 0
+	}
+	all, noEnvido := d.pairCounts(a, b)
+	if isEnvido {
+		return all - noEnvido
+	}
+	return noEnvido
+}
+
+// Chance of being dealt a pair of the matrix, as in Pair, given that the hand holds every card of held
+// (eg. the cards a player already played). held must not be in the known cards of the odds.
+func (d DealOdds) PairHolding(r0, r1 string, isEnvido bool, held []Card) float64 {
+	if len(held) == 0 {
+		return d.Pair(r0, r1, isEnvido)
+	}
+	a, b := RANKS[r0], RANKS[r1]
+	if a == 0 || b == 0 || a > 14 || b > 14 || len(held) > 3 {
+		return 0
+	}
+
+	rest := d
+	for _, c := range held {
+		if rest.bySuit[suitIndex(c.S)][c.Truco()] == 0 {
+			return 0
+		}
+		rest.bySuit[suitIndex(c.S)][c.Truco()]--
+		rest.count--
+	}
+	hands := 1
+	if len(held) < 3 {
+		hands = int(math.PickC(rest.count, 3-len(held)))
+	}
+	if hands == 0 {
+		return 0
+	}
+
+	all, noEnvido := rest.heldPairCounts(a, b, held)
+	if isEnvido {
+		return float64(all-noEnvido) / float64(hands)
+	}
+	return float64(noEnvido) / float64(hands)
+}
+
+// Amount of hands as pairCounts, holding every card of held (not unseen in d).
+//
+// A hand of the pair is made of 3 slots of ranks (eg. a, b and lower than b):
+// held cards take their slots, and the rest are counted by suit.
+func (d DealOdds) heldPairCounts(a, b uint8, held []Card) (all, noEnvido int) {
+	if a < b {
+		a, b = b, a
+	}
+	// slots as ranges of truco ranks
+	hands := [][][2]uint8{{{a, a}, {b, b}, {1, b - 1}}, {{a, a}, {b, b}, {b, b}}}
+	if a == b {
+		hands = [][][2]uint8{{{a, a}, {a, a}, {1, b - 1}}, {{a, a}, {a, a}, {a, a}}}
+	}
+
+	for _, slots := range hands {
+		free := slices.Clone(slots)
+		suits := make([]int, 0, 3)
+		for _, c := range held {
+			i := slices.IndexFunc(free, func(slot [2]uint8) bool {
+				return slot[0] <= c.Truco() && c.Truco() <= slot[1]
+			})
+			if i == -1 {
+				free = nil
+				break
+			}
+			free = slices.Delete(free, i, i+1)
+			suits = append(suits, suitIndex(c.S))
+		}
+		if free == nil {
+			continue
+		}
+
+		slotAll, slotNoEnvido := d.slotCounts(free, suits)
+		// the same rank drawn twice is counted in both orders
+		for i := range free {
+			if i > 0 && free[i] == free[i-1] {
+				slotAll, slotNoEnvido = slotAll/(i+1), slotNoEnvido/(i+1)
+			}
+		}
+		all += slotAll
+		noEnvido += slotNoEnvido
+	}
+	return all, noEnvido
+}
+
+// Ways of filling free slots with unseen cards, in order, and how many of them
+// have no 2 cards of the same suit, along with cards of suits
+func (d DealOdds) slotCounts(free [][2]uint8, suits []int) (all, noEnvido int) {
+	if len(free) == 0 {
+		all = 1
+		if len(suits) == 3 && suits[0] != suits[1] && suits[1] != suits[2] && suits[0] != suits[2] {
+			noEnvido = 1
+		}
+		return all, noEnvido
+	}
+	for s, n := range d.suitCounts(free[0][0], free[0][1]) {
+		if n == 0 {
+			continue
+		}
+		// a single card of each suit and rank: the same slot again takes another suit
+		rest := d
+		if free[0][0] == free[0][1] {
+			rest.bySuit[s][free[0][0]] = 0
+		}
+		restAll, restNoEnvido := rest.slotCounts(free[1:], append(slices.Clone(suits), s))
+		all += n * restAll
+		noEnvido += n * restNoEnvido
+	}
+	return all, noEnvido
+}
+
+// Amount of hands whose 2 highest cards are of ranks a and b,
+// and how many of them have no 2 cards of the same suit
+func (d DealOdds) pairCounts(a, b uint8) (all, noEnvido int) {
+	if a < b {
+		a, b = b, a
+	}
+	aSuits := d.suitCounts(a, a)
+	bSuits := d.suitCounts(b, b)
+	lower := d.suitCounts(1, b-1)
+	nA, nB, nLower := sum(aSuits), sum(bSuits), sum(lower)
+
+	if a == b {
+		// 2 cards of rank a and a lower card, or 3 cards of rank a
+		all = int(math.PickC(nA, 2))*nLower + int(math.PickC(nA, 3))
+		for s := range 4 {
+			for t := s + 1; t < 4; t++ {
+				noEnvido += aSuits[s] * aSuits[t] * (nLower - lower[s] - lower[t])
+			}
+		}
+		noEnvido += distinctSuits(aSuits)
+		return all, noEnvido
+	}
+
+	// a card of rank a, a card of rank b and a lower card, or a card of rank a and 2 of rank b
+	all = nA*nB*nLower + nA*int(math.PickC(nB, 2))
+	for s := range 4 {
+		for t := range 4 {
+			if s != t {
+				noEnvido += aSuits[s] * bSuits[t] * (nLower - lower[s] - lower[t])
+			}
+		}
+		for t := range 4 {
+			for u := t + 1; u < 4; u++ {
+				if t != s && u != s {
+					noEnvido += aSuits[s] * bSuits[t] * bSuits[u]
+				}
+			}
+		}
+	}
+	return all, noEnvido
+}
+
+// Unseen cards of each suit, with truco rank between lo and hi
+func (d DealOdds) suitCounts(lo, hi uint8) [4]int {
+	var counts [4]int
+	for s := range 4 {
+		for r := lo; r <= hi; r++ {
+			counts[s] += d.bySuit[s][r]
+		}
+	}
+	return counts
+}
+
+// Ways of picking 3 cards of 3 different suits
+func distinctSuits(counts [4]int) int {
+	var ways int
+	for s := range 4 {
+		for t := s + 1; t < 4; t++ {
+			for u := t + 1; u < 4; u++ {
+				ways += counts[s] * counts[t] * counts[u]
+			}
+		}
+	}
+	return ways
+}
+
+func sum(counts [4]int) int {
+	return counts[0] + counts[1] + counts[2] + counts[3]
+}
+
+// Chance of being dealt one of count hands
+func (d DealOdds) p(count int) float64 {
+	hands := d.Hands()
+	if hands == 0 {
+		return 0
+	}
+	return float64(count) / float64(hands)
+}
@@ -0,0 +1,141 @@
+package truco
+
+import (
+	gomath "math"
+	"slices"
+	"testing"
+	"truco/pkg/math"
+)
+
+// Counts as ComputePairStats, enumerating every hand
+func enumeratePairs(kCards []Card) (map[StatsKey]int, int, int, int) {
+	pairs := make(map[StatsKey]int)
+	var hands, flores, envidos int
+	for h := range math.Combinations(CardsExcluding(ALL_CARDS, kCards), 3) {
+		hand := Hand(h)
+		slices.SortFunc(hand, SortForTruco)
+		envido := slices.Clone(hand).Envido()
+		pairs[StatsKey{pair: hand[0].ToRank() + " " + hand[1].ToRank(), isEnvido: envido >= 20}]++
+		hands++
+		if hand[0].S == hand[1].S && hand[1].S == hand[2].S {
+			flores++
+		}
+		if envido >= 20 {
+			envidos++
+		}
+	}
+	return pairs, hands, flores, envidos
+}
+
+func TestDealOddsPairs(t *testing.T) {
+	for _, kCards := range [][]Card{
+		{},
+		{{1, 'e'}, {3, 'b'}, {3, 'o'}},
+		{{7, 'o'}, {7, 'c'}, {12, 'e'}, {4, 'b'}, {6, 'o'}},
+	} {
+		d := NewDealOdds(kCards)
+		pairs, hands, flores, envidos := enumeratePairs(kCards)
+		if d.Hands() != hands {
+			t.Errorf("kCards=%v: expected %d hands, got %d", kCards, hands, d.Hands())
+		}
+		if gomath.Abs(d.Flor()-float64(flores)/float64(hands)) > 1e-9 {
+			t.Errorf("kCards=%v: expected flor %f, got %f", kCards, float64(flores)/float64(hands), d.Flor())
+		}
+		if gomath.Abs(d.Envido()-float64(envidos)/float64(hands)) > 1e-9 {
+			t.Errorf("kCards=%v: expected envido %f, got %f", kCards, float64(envidos)/float64(hands), d.Envido())
+		}
+
+		var total int
+		for r0 := range RANKS {
+			for r1 := range RANKS {
+				if RANKS[r0] > 14 || RANKS[r1] > 14 || RANKS[r0] < RANKS[r1] {
+					continue
+				}
+				for _, isEnvido := range []bool{true, false} {
+					count := d.PairCount(r0, r1, isEnvido)
+					if count != pairs[StatsKey{pair: r0 + " " + r1, isEnvido: isEnvido}] {
+						t.Errorf("kCards=%v: expected %d hands for %s %s %v, got %d", kCards, pairs[StatsKey{pair: r0 + " " + r1, isEnvido: isEnvido}], r0, r1, isEnvido, count)
+					}
+					total += count
+				}
+			}
+		}
+		if total != hands {
+			t.Errorf("kCards=%v: expected pairs to add up to %d hands, got %d", kCards, hands, total)
+		}
+	}
+}
+
+func TestDealOdds(t *testing.T) {
+	d := NewDealOdds([]Card{})
+	if d.Hand(NewHand("1e 7o 3c")) != 1.0/9880 {
+		t.Errorf("Expected 1/9880 for any hand, got %f", d.Hand(NewHand("1e 7o 3c")))
+	}
+	// 1 - C(36,3)/C(40,3)
+	if gomath.Abs(d.Brava()-(1-7140.0/9880)) > 1e-9 {
+		t.Errorf("Expected %f chance of a brava, got %f", 1-7140.0/9880, d.Brava())
+	}
+
+	d = NewDealOdds([]Card{{1, 'e'}, {7, 'e'}})
+	if d.Hand(NewHand("1e 7o 3c")) != 0 {
+		t.Errorf("Expected no chance of a hand with known cards")
+	}
+	if d.Pair("1e", "7o", false) != 0 {
+		t.Errorf("Expected no chance of a pair with known cards")
+	}
+	// 1 - C(36,3)/C(38,3)
+	if gomath.Abs(d.Brava()-(1-7140.0/8436)) > 1e-9 {
+		t.Errorf("Expected %f chance of a brava, got %f", 1-7140.0/8436, d.Brava())
+	}
+}
+
+// Counts as enumeratePairs, for the hands that hold every card of held
+func enumeratePairsHolding(kCards, held []Card) (map[StatsKey]int, int) {
+	pairs := make(map[StatsKey]int)
+	var hands int
+	for h := range math.Combinations(CardsExcluding(ALL_CARDS, slices.Concat(kCards, held)), 3-len(held)) {
+		hand := Hand(slices.Concat(held, h))
+		slices.SortFunc(hand, SortForTruco)
+		envido := slices.Clone(hand).Envido()
+		pairs[StatsKey{pair: hand[0].ToRank() + " " + hand[1].ToRank(), isEnvido: envido >= 20}]++
+		hands++
+	}
+	return pairs, hands
+}
+
+func TestDealOddsPairHolding(t *testing.T) {
+	kCards := []Card{{7, 'o'}, {12, 'e'}, {4, 'b'}}
+	d := NewDealOdds(kCards)
+	for _, held := range [][]Card{
+		{{1, 'e'}},
+		{{3, 'b'}, {3, 'o'}},
+		{{6, 'c'}, {1, 'b'}},
+		{{5, 'c'}, {5, 'o'}, {5, 'e'}},
+	} {
+		pairs, hands := enumeratePairsHolding(kCards, held)
+
+		var total float64
+		for r0 := range RANKS {
+			for r1 := range RANKS {
+				if RANKS[r0] > 14 || RANKS[r1] > 14 || RANKS[r0] < RANKS[r1] {
+					continue
+				}
+				for _, isEnvido := range []bool{true, false} {
+					expected := float64(pairs[StatsKey{pair: r0 + " " + r1, isEnvido: isEnvido}]) / float64(hands)
+					p := d.PairHolding(r0, r1, isEnvido, held)
+					if gomath.Abs(p-expected) > 1e-9 {
+						t.Errorf("held=%v: expected %f for %s %s %v, got %f", held, expected, r0, r1, isEnvido, p)
+					}
+					total += p
+				}
+			}
+		}
+		if gomath.Abs(total-1) > 1e-9 {
+			t.Errorf("held=%v: expected pairs to add up to 1, got %f", held, total)
+		}
+	}
+
+	if d.PairHolding("7o", "3", false, []Card{{7, 'o'}}) != 0 {
+		t.Errorf("Expected no chance holding a known card")
+	}
+}
@@ -0,0 +1,153 @@
+package truco
+
+import (
+	"slices"
+	"truco/pkg/math"
+)
+
+// OpponentModel decides how the opponent plays its hand against a permutation of mHand.
+//
+// Parameters of Versus:
+//   - mPerm: my hand, in the order I play it
+//   - oHand: opponent hand, in any order: the model decides how it is played
+//   - kCards: cards the opponent already played, in the order played (these can't move)
+//   - r: rules of the variant played
+//   - isMHandFirst: boolean controling who plays first in round 0
+//
+// Returns the games mPerm wins, out of the games counted.
+type OpponentModel interface {
+	Versus(mPerm, oHand Hand, kCards []Card, r Ruleset, isMHandFirst bool) (wins, count int)
+}
+
+// Opponent models by name, as used by the frontend
+var OPPONENT_MODELS = map[string]OpponentModel{
+	"uniform":    UniformOpponent{},
+	"reasonable": ReasonableOpponent{},
+	"best":       BestResponseOpponent{},
+}
+
+// UniformOpponent plays every permutation of its hand with the same chance:
+// it commits to an order without looking at our cards.
+type UniformOpponent struct{}
+
+func (UniformOpponent) Versus(mPerm, oHand Hand, kCards []Card, r Ruleset, isMHandFirst bool) (wins, count int) {
+	for _, oPerm := range math.PermutationsRaw(oHand, 3) {
+		if Hand(oPerm).HasAllInPlace(kCards) {
+			wins += r.Beats(mPerm, oPerm)
+			count++
+		}
+	}
+	return wins, count
+}
+
+// ReasonableOpponent plays every permutation of its hand that is reasonably played
+// (see IsReasonablyPlayed) with the same chance.
+//
+// Note that the check is made on both hands: permutations of mHand that
+// are not reasonable count no games.
+type ReasonableOpponent struct{}
+
+func (ReasonableOpponent) Versus(mPerm, oHand Hand, kCards []Card, r Ruleset, isMHandFirst bool) (wins, count int) {
+	for _, oPerm := range math.PermutationsRaw(oHand, 3) {
+		if !Hand(oPerm).HasAllInPlace(kCards) {
+			continue
+		}
+
+		var isReasonablyPlayed bool
+		if isMHandFirst {
+			isReasonablyPlayed = isReasonablyPlayedRanks(handRanks(r, mPerm), handRanks(r, oPerm))
+		} else {
+			isReasonablyPlayed = isReasonablyPlayedRanks(handRanks(r, oPerm), handRanks(r, mPerm))
+		}
+
+		if isReasonablyPlayed {
+			wins += r.Beats(mPerm, oPerm)
+			count++
+		}
+	}
+	return wins, count
+}
+
+// BestResponseOpponent picks each card after seeing ours, trick by trick:
+// it only knows the cards we already played, not the ones still in our hand.
+//   - answering our card: it plays the lowest card that wins the hand, else the trick,
+//     else ties it (parda), else its lowest card
+//   - leading: it plays its highest card, before seeing ours
+//
+// Its cards in kCards are played where they were played.
+// Each hand counts as many games as its orders that keep kCards in place, as in UniformOpponent,
+// so the stats of every model weigh the hands of the opponent the same.
+type BestResponseOpponent struct{}
+
+func (BestResponseOpponent) Versus(mPerm, oHand Hand, kCards []Card, r Ruleset, isMHandFirst bool) (wins, count int) {
+	for _, oPerm := range math.PermutationsRaw(oHand, 3) {
+		if Hand(oPerm).HasAllInPlace(kCards) {
+			count++
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return bestResponse(solver{r: r}, mPerm, oHand, kCards, isMHandFirst) * count, count
+}
+
+// Plays mPerm against the adaptive opponent of BestResponseOpponent.
+// Same trick rules as Minimax.
+//
+// Returns 1 if mPerm takes the hand, 0 if tie or loss (as Ruleset.Beats)
+func bestResponse(s solver, mPerm, oHand Hand, kCards []Card, mLeads bool) int {
+	oRem := CardsExcluding(oHand, kCards)
+	results := make([]int, 0, 3)
+	for trick, mCard := range mPerm {
+		if res, done := trucoResult(results); done {
+			return resultValue(res)
+		}
+
+		var oCard Card
+		if trick < len(kCards) {
+			oCard = kCards[trick]
+		} else {
+			if mLeads {
+				oCard = s.reply(mCard, oRem, results)
+			} else {
+				oCard = s.lead(oRem)
+			}
+			oRem = CardsExcluding(oRem, []Card{oCard})
+		}
+
+		res := s.trick(mCard, oCard)
+		results = append(results, res)
+		mLeads = s.nextLeads(mLeads, res)
+	}
+	res, _ := trucoResult(results)
+	return resultValue(res)
+}
+
+// Card of the opponent that answers mCard: the lowest that wins the hand,
+// else the lowest that wins the trick, else ties it, else the lowest card
+func (s solver) reply(mCard Card, oRem []Card, results []int) Card {
+	cards := slices.Clone(oRem)
+	slices.SortStableFunc(cards, func(a, b Card) int {
+		return int(s.r.Rank(a)) - int(s.r.Rank(b))
+	})
+
+	best, bestScore := cards[0], -3
+	for _, c := range cards {
+		// seen by the opponent: 1 win, -1 loss, 0 tie
+		score := -s.trick(mCard, c)
+		if res, done := trucoResult(append(slices.Clone(results), -score)); done {
+			score = -2 * res
+		}
+		if score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	return best
+}
+
+// Card the opponent leads with, before seeing ours: its highest
+func (s solver) lead(oRem []Card) Card {
+	return slices.MaxFunc(oRem, func(a, b Card) int {
+		return int(s.r.Rank(a)) - int(s.r.Rank(b))
+	})
+}
@@ -0,0 +1,77 @@
+package truco
+
+import (
+	"testing"
+)
+
+func TestOpponentModels(t *testing.T) {
+	mPerm := NewHand("1e 4c 4o")
+	oHand := NewHand("3e 3b 3o")
+
+	wins, count := UniformOpponent{}.Versus(mPerm, oHand, []Card{}, ArgentineRules{}, true)
+	if wins != 0 || count != 6 {
+		t.Errorf("Uniform: expected 0 wins of 6, got %d of %d", wins, count)
+	}
+
+	wins, count = UniformOpponent{}.Versus(mPerm, oHand, []Card{{3, 'b'}}, ArgentineRules{}, true)
+	if count != 2 {
+		t.Errorf("Uniform: expected 2 permutations with 3b first, got %d", count)
+	}
+
+	// 7e and 1e beat every card of the opponent
+	mPerm = NewHand("1e 7e 4o")
+	oHand = NewHand("3e 2b 5o")
+	wins, count = UniformOpponent{}.Versus(mPerm, oHand, []Card{}, ArgentineRules{}, true)
+	if wins != 6 || count != 6 {
+		t.Errorf("Uniform: expected 6 wins of 6, got %d of %d", wins, count)
+	}
+	wins, count = BestResponseOpponent{}.Versus(mPerm, oHand, []Card{}, ArgentineRules{}, true)
+	if wins != 6 || count != 6 {
+		t.Errorf("Best response: expected 6 wins of 6, got %d of %d", wins, count)
+	}
+
+	// the opponent beats 4o 3c 1e by answering 4o with 5o, and leading 3e to tie 3c
+	mPerm = NewHand("4o 3c 1e")
+	wins, _ = UniformOpponent{}.Versus(mPerm, oHand, []Card{}, ArgentineRules{}, true)
+	if wins == 0 {
+		t.Errorf("Uniform: expected some wins, got none")
+	}
+	wins, _ = BestResponseOpponent{}.Versus(mPerm, oHand, []Card{}, ArgentineRules{}, true)
+	if wins != 0 {
+		t.Errorf("Best response: expected a loss, got %d wins", wins)
+	}
+
+	// leading, it plays its highest cards first: 3e takes 4o, but 2b and 5o lose to 3c and 1e
+	wins, count = BestResponseOpponent{}.Versus(mPerm, oHand, []Card{}, ArgentineRules{}, false)
+	if wins != 6 || count != 6 {
+		t.Errorf("Best response leading: expected 6 wins of 6, got %d of %d", wins, count)
+	}
+
+	// it doesn't see our next cards: 7e takes 4o, then 1b leads into 1e and 3c wins the last trick.
+	// Knowing 1e comes next, it would have kept 1b for 3c
+	wins, _ = BestResponseOpponent{}.Versus(NewHand("4o 1e 3c"), NewHand("1b 7e 4e"), []Card{}, ArgentineRules{}, true)
+	if wins != 6 {
+		t.Errorf("Best response: expected 6 wins, got %d", wins)
+	}
+
+	// can't play an order that doesn't keep kCards in place
+	_, count = BestResponseOpponent{}.Versus(mPerm, oHand, []Card{{1, 'e'}}, ArgentineRules{}, true)
+	if count != 0 {
+		t.Errorf("Best response: expected no games, got %d", count)
+	}
+}
+
+// An opponent that adapts can only do better than one that doesn't
+func TestBestResponseStrength(t *testing.T) {
+	for _, hs := range []string{"7e 12c 2o", "1b 6o 6c"} {
+		uniform := NewHand(hs).TrucoStrengthStats([]Card{}, []Card{}, EnvidoAny(), true, UniformOpponent{})
+		best := NewHand(hs).TrucoStrengthStats([]Card{}, []Card{}, EnvidoAny(), true, BestResponseOpponent{})
+
+		if best.StrengthAll > uniform.StrengthAll {
+			t.Errorf("Hand %s: best response strength %f should be <= uniform %f", hs, best.StrengthAll, uniform.StrengthAll)
+		}
+		if best.Count != uniform.Count {
+			t.Errorf("Hand %s: expected as many games as uniform %d, got %d", hs, uniform.Count, best.Count)
+		}
+	}
+}
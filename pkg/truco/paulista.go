@@ -0,0 +1,90 @@
+package truco
+
+import (
+	"slices"
+	"truco/pkg/math"
+)
+
+// Truco paulista (brazilian truco).
+//
+// A card is turned up, the 'vira': the next number in PAULISTA_ORDER makes the 4 'manilhas',
+// that beat every other card, in the order of MANILHA_SUITS.
+// Every other card ranks only by its number: there are no special aces and sevens.
+// There is no envido nor flor, and hands are worth 1, 3, 6, 9 or 12 points.
+
+// Numbers of truco paulista, lowest first
+var PAULISTA_ORDER = []uint8{4, 5, 6, 7, 10, 11, 12, 1, 2, 3}
+
+// Suits of the manilhas, lowest first: ouros, espadas, copas, paus
+var MANILHA_SUITS = []uint8{'o', 'e', 'c', 'b'}
+
+// Bets of truco paulista: truco, seis, nove and doze. No envido
+var BETS_BR = BetSchedule{Truco: []uint8{1, 3, 6, 9, 12}, Raises: []string{"Truco", "Seis", "Nove", "Doze"}, Eleven: true}
+
+// Returns the number of the manilhas, given v=vira Card: the next number in PAULISTA_ORDER
+func Manilha(v Card) uint8 {
+	i := slices.Index(PAULISTA_ORDER, v.N)
+	return PAULISTA_ORDER[(i+1)%len(PAULISTA_ORDER)]
+}
+
+// Truco value of a card in truco paulista, given v=vira Card:
+//   - 1 to 10 for the numbers of PAULISTA_ORDER
+//   - 11 to 14 for the manilhas, in the order of MANILHA_SUITS
+//   - 0 if the card doesn't exist
+func GetTrucoBR(c, v Card) uint8 {
+	if c.N == Manilha(v) {
+		return 11 + uint8(slices.Index(MANILHA_SUITS, c.S))
+	}
+	return uint8(slices.Index(PAULISTA_ORDER, c.N) + 1)
+}
+
+func (c Card) TrucoBR(v Card) uint8 {
+	return GetTrucoBR(c, v)
+}
+
+// Truco paulista: the vira makes the manilhas, there's no envido, and hands go up to 12 points
+type PaulistaRules struct {
+	V Card // vira
+}
+
+func (r PaulistaRules) Name() string        { return "BR" }
+func (r PaulistaRules) Deck() []Card        { return CardsExcluding(ALL_CARDS, []Card{r.V}) }
+func (r PaulistaRules) Muestra() Card       { return r.V }
+func (r PaulistaRules) Rank(c Card) uint8   { return GetTrucoBR(c, r.V) }
+func (r PaulistaRules) HasFlor() bool       { return false }
+func (r PaulistaRules) MaxEnvido() uint8    { return 0 }
+func (r PaulistaRules) Bets() BetSchedule   { return BETS_BR }
+func (r PaulistaRules) Envido(h Hand) uint8 { return 0 }
+
+func (r PaulistaRules) Beats(mHand, oHand Hand) int {
+	return trucoBeatsRanks(handRanks(r, mHand), handRanks(r, oHand))
+}
+
+// strength of a hand in truco paulista (brute force)
+//
+// plays the hand against all other hands, in all possible permutations, with all possible viras.
+// counts times it wins. Normalizes result to a percent.
+// range of score = (0 to 1)
+func (mHand Hand) TrucoStrengthBR() float32 {
+	return trucoStrengthTurned(mHand, func(v Card) Ruleset { return PaulistaRules{V: v} })
+}
+
+// Creates a csv file as CreateHandStatsCSV, for truco paulista:
+// strength is TrucoStrengthBR, and envido is always 0.
+//
+// There are no classes of hands to share: any card can be a manilha.
+func CreateHandStatsCSVBR(outputPath string) error {
+	var hands []Hand
+	for h := range math.Combinations(ALL_CARDS, 3) {
+		hand := Hand(h)
+		slices.SortFunc(hand, SortForTruco)
+		hands = append(hands, hand)
+	}
+
+	strengths := parallelStrengths(hands, Hand.TrucoStrengthBR)
+	rows := make([]handStatsRow, len(hands))
+	for i, hand := range hands {
+		rows[i] = handStatsRow{hand: hand, strength: strengths[i], combined: strengths[i]}
+	}
+	return writeHandStatsCSV(outputPath, rows)
+}
@@ -0,0 +1,77 @@
+package truco
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestManilha(t *testing.T) {
+	for vira, manilha := range map[Card]uint8{{4, 'e'}: 5, {7, 'o'}: 10, {12, 'c'}: 1, {3, 'b'}: 4} {
+		if m := Manilha(vira); m != manilha {
+			t.Errorf("vira %s: expected manilhas of %d, got %d", vira.ToString(), manilha, m)
+		}
+	}
+}
+
+func TestTrucoBR(t *testing.T) {
+	v := Card{3, 'e'} // manilhas are the 4s
+
+	// manilhas beat every card: paus, copas, espadas, ouros
+	order := []Card{{4, 'b'}, {4, 'c'}, {4, 'e'}, {4, 'o'}, {3, 'b'}, {2, 'c'}, {1, 'o'}, {12, 'e'}, {11, 'b'}, {10, 'c'}, {7, 'e'}, {6, 'o'}, {5, 'b'}}
+	for i := 1; i < len(order); i++ {
+		if order[i-1].TrucoBR(v) <= order[i].TrucoBR(v) {
+			t.Errorf("%s should beat %s", order[i-1].ToString(), order[i].ToString())
+		}
+	}
+	// no special aces nor sevens
+	if GetTrucoBR(Card{1, 'e'}, v) != GetTrucoBR(Card{1, 'c'}, v) || GetTrucoBR(Card{7, 'e'}, v) != GetTrucoBR(Card{7, 'c'}, v) {
+		t.Errorf("expected aces and sevens of every suit to tie")
+	}
+
+	r := PaulistaRules{V: v}
+	if len(r.Deck()) != 39 || slices.Contains(r.Deck(), v) {
+		t.Errorf("expected 39 cards without the vira, got %d", len(r.Deck()))
+	}
+	if r.Envido(NewHand("5c 7c 6c")) != 0 {
+		t.Errorf("expected no envido")
+	}
+	if rules, err := NewRuleset("BR", v); err != nil || rules != r {
+		t.Errorf("expected truco paulista, got %v, %v", rules, err)
+	}
+	if r.Beats(NewHand("4o 5e 6e"), NewHand("3b 3c 2e")) != 0 || r.Beats(NewHand("4o 4e 6e"), NewHand("3b 3c 2e")) != 1 {
+		t.Errorf("expected a single manilha to lose, and two to win")
+	}
+}
+
+func TestTrucoStrengthBR(t *testing.T) {
+	strong, weak := NewHand("3e 3b 2o").TrucoStrengthBR(), NewHand("4e 5b 6o").TrucoStrengthBR()
+	if strong <= weak || strong > 1 || weak < 0 {
+		t.Errorf("expected 0 <= %f < %f <= 1", weak, strong)
+	}
+}
+
+// The tracker evaluates a match in its rules: the vira is not a muestra
+func TestTrackerRulesBR(t *testing.T) {
+	r := PaulistaRules{V: Card{3, 'e'}}
+
+	hist := EnvidoDistributionRules(r, EnvidoAny(), []Card{}, []Card{})
+	if len(hist.Buckets) != 1 || hist.Buckets[0].Envido != 0 || hist.Count != 9139 {
+		t.Errorf("expected every hand of the 39 cards without envido, got %v of %d", hist.Buckets, hist.Count)
+	}
+
+	seats := []SeatView{{Hand: NewHand("4c 1e 2b")}, {}}
+	loc, err := CardLocationsRules(r, seats, 200, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if i := slices.Index(loc.Cards, r.V); loc.P[i][1] != 0 {
+		t.Errorf("expected no seat to hold the vira, got %f", loc.P[i][1])
+	}
+
+	// the 2 highest manilhas (4b, 4c) take 2 tricks against any hand: every card wins, the cheapest is led
+	view := PlayView{MPlayed: []Card{}, OPlayed: []Card{}, OEnvido: EnvidoAny(), IsMHandFirst: true}
+	res, err := SuggestCardRules(r, NewHand("4c 4b 5e"), view, 100, 1)
+	if err != nil || res.Card != (Card{5, 'e'}) || slices.Min(res.Wins) != 1 {
+		t.Errorf("expected to always win leading 5e, got %v (%v)", res, err)
+	}
+}
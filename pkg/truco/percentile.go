@@ -0,0 +1,114 @@
+package truco
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Amount of players at the table for HandRank.BestTruco and HandRank.BestEnvido
+var TABLE_SIZES = []int{2, 4, 6}
+
+// Where a hand stands among the hands still possible, for argentinian truco
+type HandRank struct {
+	Count            int     // hands still possible
+	TrucoPercentile  float32 // % of possible hands with lower truco strength, ties count half
+	TrucoDecile      int     // 1-10, 10 is the strongest
+	EnvidoPercentile float32 // % of possible hands with lower envido, ties count half
+	EnvidoDecile     int     // 1-10, 10 is the strongest
+
+	// chance of holding the strongest hand at the table, per TABLE_SIZES:
+	// the other players are dealt from the cards still unseen (ties count as shared)
+	BestTruco  []float32
+	BestEnvido []float32
+}
+
+// RankHand ranks mHand among every hand that doesn't hold mHand nor kCards, given:
+//   - records: the hand table, for truco strengths (see LoadHandRecords)
+//   - samples: deals simulated for BestTruco and BestEnvido
+//   - seed: seed of the dealer, same seed gives same result
+//
+// Returns an error if mHand is not in records.
+func RankHand(mHand Hand, kCards []Card, records []HandRecord, samples int, seed uint64) (HandRank, error) {
+	strengths := make(map[[3]Card]float64, len(records))
+	for _, r := range records {
+		strengths[rangeKey(r.Hand)] = r.Strength
+	}
+	mStrength, ok := strengths[rangeKey(mHand)]
+	if !ok {
+		return HandRank{}, fmt.Errorf("Unknown hand %s", mHand.ToString())
+	}
+	mEnvido := slices.Clone(mHand).Envido()
+
+	unseen := CardsExcluding(ALL_CARDS, slices.Concat(mHand, kCards))
+	rank := HandRank{}
+	var trucoBelow, envidoBelow float64
+	for _, r := range records {
+		if len(CardsExcluding(r.Hand, unseen)) != 0 {
+			continue
+		}
+		rank.Count++
+		trucoBelow += below(r.Strength, mStrength)
+		envidoBelow += below(float64(r.Envido), float64(mEnvido))
+	}
+	if rank.Count > 0 {
+		rank.TrucoPercentile = float32(100 * trucoBelow / float64(rank.Count))
+		rank.EnvidoPercentile = float32(100 * envidoBelow / float64(rank.Count))
+	}
+	rank.TrucoDecile = decile(rank.TrucoPercentile)
+	rank.EnvidoDecile = decile(rank.EnvidoPercentile)
+
+	rank.BestTruco = make([]float32, len(TABLE_SIZES))
+	rank.BestEnvido = make([]float32, len(TABLE_SIZES))
+	deck := NewDeckOf(unseen, seed)
+	for i, players := range TABLE_SIZES {
+		if samples < 1 || len(unseen) < 3*(players-1) {
+			continue
+		}
+
+		var trucoWins, envidoWins float64
+		for range samples {
+			deck.Reset()
+			oStrengths := make([]float64, 0, players-1)
+			oEnvidos := make([]float64, 0, players-1)
+			for range players - 1 {
+				cards, _ := deck.Draw(3)
+				oHand := Hand(cards)
+				oStrengths = append(oStrengths, strengths[rangeKey(oHand)])
+				oEnvidos = append(oEnvidos, float64(oHand.Envido()))
+			}
+			trucoWins += bestShare(mStrength, oStrengths)
+			envidoWins += bestShare(float64(mEnvido), oEnvidos)
+		}
+		rank.BestTruco[i] = float32(trucoWins / float64(samples))
+		rank.BestEnvido[i] = float32(envidoWins / float64(samples))
+	}
+	return rank, nil
+}
+
+// 1 if v is lower than m, 0.5 on ties
+func below(v, m float64) float64 {
+	if v < m {
+		return 1
+	} else if v == m {
+		return 0.5
+	}
+	return 0
+}
+
+// Share of the win of m against others: 1 if it is the highest, split on ties
+func bestShare(m float64, others []float64) float64 {
+	ties := 1
+	for _, o := range others {
+		if o > m {
+			return 0
+		} else if o == m {
+			ties++
+		}
+	}
+	return 1 / float64(ties)
+}
+
+// Decile of a percentile: 1-10
+func decile(percentile float32) int {
+	return min(10, int(percentile/10)+1)
+}
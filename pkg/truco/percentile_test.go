@@ -0,0 +1,69 @@
+package truco
+
+import (
+	gomath "math"
+	"slices"
+	"testing"
+	"truco/pkg/math"
+)
+
+// Hand table with the sum of truco ranks as strength
+func sumRecords() []HandRecord {
+	records := make([]HandRecord, 0)
+	for h := range math.Combinations(ALL_CARDS, 3) {
+		hand := Hand(h)
+		var sum float64
+		for _, c := range hand {
+			sum += float64(c.Truco())
+		}
+		records = append(records, HandRecord{Hand: hand, Strength: sum, Envido: slices.Clone(hand).Envido()})
+	}
+	return records
+}
+
+func TestRankHand(t *testing.T) {
+	records := sumRecords()
+
+	rank, err := RankHand(NewHand("1e 1b 7e"), []Card{}, records, 500, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if rank.Count != 7770 {
+		t.Errorf("Expected 7770 possible hands, got %d", rank.Count)
+	}
+	if rank.TrucoDecile != 10 || rank.TrucoPercentile < 99.9 {
+		t.Errorf("Expected the best truco hand in the top decile, got %f (%d)", rank.TrucoPercentile, rank.TrucoDecile)
+	}
+	for i := range TABLE_SIZES {
+		if rank.BestTruco[i] != 1 {
+			t.Errorf("Expected the best truco hand to always win with %d players, got %f", TABLE_SIZES[i], rank.BestTruco[i])
+		}
+	}
+
+	// the other 4 can't make a lower hand
+	rank, _ = RankHand(NewHand("4e 4b 4o"), []Card{}, records, 500, 1)
+	if rank.TrucoDecile != 1 || rank.BestTruco[0] != 0 {
+		t.Errorf("Expected the worst truco hand to never win, got decile %d and %f", rank.TrucoDecile, rank.BestTruco[0])
+	}
+
+	// 2 players: best hand as often as the other hand is lower
+	rank, _ = RankHand(NewHand("3e 2o 6c"), []Card{{1, 'e'}, {7, 'o'}}, records, 4000, 1)
+	if rank.Count != 6545 {
+		t.Errorf("Expected 6545 possible hands with 2 known cards, got %d", rank.Count)
+	}
+	if gomath.Abs(float64(rank.BestTruco[0])-float64(rank.TrucoPercentile)/100) > 0.03 {
+		t.Errorf("Expected chance of best truco near %f, got %f", rank.TrucoPercentile/100, rank.BestTruco[0])
+	}
+	if gomath.Abs(float64(rank.BestEnvido[0])-float64(rank.EnvidoPercentile)/100) > 0.03 {
+		t.Errorf("Expected chance of best envido near %f, got %f", rank.EnvidoPercentile/100, rank.BestEnvido[0])
+	}
+	for i := 1; i < len(TABLE_SIZES); i++ {
+		if rank.BestTruco[i] > rank.BestTruco[i-1] {
+			t.Errorf("Expected lower chance of best truco with more players, got %v", rank.BestTruco)
+		}
+	}
+
+	if _, err := RankHand(NewHand("1e 1b 7e"), []Card{}, records[100:110], 10, 1); err == nil {
+		t.Errorf("Expected error for a hand not in the records")
+	}
+}
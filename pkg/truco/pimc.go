@@ -0,0 +1,126 @@
+package truco
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+)
+
+// Perfect-information Monte-Carlo (PIMC) search for the card play:
+// we don't know the rival's hand, so we sample hands it could hold,
+// solve each one with Minimax as if we could see it, and add up the results.
+//
+// Known weakness of PIMC: every sample assumes we'll know the rival's hand
+// in the next tricks. It is a good heuristic for choosing the card to play now.
+
+// What a player knows of the hand when choosing a card, against a single rival.
+//
+// With partners, the tricks played are taken as they ended at the whole table (Results),
+// and the rest of the hand is played against the rival alone.
+type PlayView struct {
+	MPlayed      []Card           // cards I played, in order
+	OPlayed      []Card           // cards the rival played, in order
+	KCards       []Card           // other known cards, that the rival can't hold
+	OEnvido      EnvidoConstraint // what we know of the envido of the rival
+	IsMHandFirst bool             // I lead the first trick
+	Results      []int            // results of the tricks ended, for my team: 1 won, -1 lost, 0 parda. Other tricks are taken from MPlayed and OPlayed
+	FixedOrder   bool             // we play every trick in the order of the first one, instead of the winner leading the next (as in the tracker)
+}
+
+// Result of SuggestCard
+type CardSuggestion struct {
+	Card    Card      // suggested card: highest win rate, lowest card on ties
+	Cards   []Card    // cards I can play now
+	Wins    []float32 // win rate of each card, over the samples
+	Samples int       // rival hands sampled
+}
+
+// SuggestCard chooses the card to play now, given:
+//   - mHand: my full hand (including cards already played)
+//   - view: what I know of the hand
+//   - m: muestra, NO_CARD for argentinian truco
+//   - samples: amount of rival hands to sample
+//   - seed: seed of the sampler, same seed gives same result
+//
+// Returns an error if it isn't my turn to play, or if no rival hand fits what we know.
+func SuggestCard(mHand Hand, view PlayView, m Card, samples int, seed uint64) (CardSuggestion, error) {
+	return SuggestCardRules(RulesFor(m), mHand, view, samples, seed)
+}
+
+// SuggestCard in the rules r, eg. of a tracked match
+func SuggestCardRules(r Ruleset, mHand Hand, view PlayView, samples int, seed uint64) (CardSuggestion, error) {
+	if samples < 1 {
+		return CardSuggestion{}, fmt.Errorf("Need at least one sample")
+	}
+	if !mHand.HasAll(view.MPlayed) {
+		return CardSuggestion{}, fmt.Errorf("Played cards must belong to the hand")
+	}
+
+	oHands := rivalRange(r, mHand, view)
+	if len(oHands) == 0 {
+		return CardSuggestion{}, fmt.Errorf("No hand fits what the rival played")
+	}
+
+	cards := CardsExcluding(mHand, view.MPlayed)
+	if len(cards) == 0 {
+		return CardSuggestion{}, fmt.Errorf("No cards left to play")
+	}
+	slices.SortStableFunc(cards, func(a, b Card) int {
+		return int(r.Rank(a)) - int(r.Rank(b))
+	})
+
+	// solving a hand is deterministic: samples may repeat,
+	// and hands that are the same under a suit symmetry are solved once (see canon.go)
+	s := solver{r: r, fixed: view.FixedOrder}
+	syms := fixingSymmetries(slices.Concat(mHand, view.OPlayed), view.KCards, s.r)
+	solved := make(map[[3]Card][]int)
+	wins := make([]int, len(cards))
+	rng := rand.New(rand.NewPCG(seed, seed))
+
+	for range samples {
+		oHand, _ := oHands[rng.IntN(len(oHands))].canonical(syms)
+		key := [3]Card(oHand)
+		values, ok := solved[key]
+		if !ok {
+			values = make([]int, len(cards))
+			for i, c := range cards {
+				res, err := s.minimax(mHand, oHand, view.IsMHandFirst, append(slices.Clone(view.MPlayed), c), view.OPlayed, view.Results)
+				if err != nil {
+					return CardSuggestion{}, fmt.Errorf("Can't play now: %w", err)
+				}
+				values[i] = res.Value
+			}
+			solved[key] = values
+		}
+		for i := range values {
+			wins[i] += values[i]
+		}
+	}
+
+	suggestion := CardSuggestion{
+		Cards:   cards,
+		Wins:    make([]float32, len(cards)),
+		Samples: samples,
+	}
+	best := 0
+	for i := range cards {
+		suggestion.Wins[i] = float32(wins[i]) / float32(samples)
+		if wins[i] > wins[best] {
+			best = i
+		}
+	}
+	suggestion.Card = cards[best]
+	return suggestion, nil
+}
+
+// Hands the rival could hold, given what we know.
+// Hands are sorted as in ALL_CARDS: sampling doesn't depend on the order of the cards played.
+func rivalRange(r Ruleset, mHand Hand, view PlayView) []Hand {
+	hands := rulesRange(r, view.OEnvido, view.OPlayed, slices.Concat(mHand, view.KCards))
+	for _, h := range hands {
+		slices.SortFunc(h, func(a, b Card) int {
+			return cardIndex(a) - cardIndex(b)
+		})
+	}
+	return hands
+}
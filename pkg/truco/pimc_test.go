@@ -0,0 +1,58 @@
+package truco
+
+import (
+	"testing"
+)
+
+func TestSuggestCard(t *testing.T) {
+	view := PlayView{MPlayed: []Card{}, OPlayed: []Card{}, OEnvido: EnvidoAny(), IsMHandFirst: true}
+
+	// every card wins: the cheapest one is played
+	res, err := SuggestCard(NewHand("1e 1b 7e"), view, NO_CARD, 200, 1)
+	if err != nil || res.Card != (Card{7, 'e'}) {
+		t.Errorf("Expected to lead 7e, got %v (%v)", res.Card, err)
+	}
+	for i, w := range res.Wins {
+		if w != 1 {
+			t.Errorf("Expected %v to always win, got %f", res.Cards[i], w)
+		}
+	}
+
+	// same seed, same samples
+	mHand := NewHand("3e 2b 6o")
+	a, _ := SuggestCard(mHand, view, NO_CARD, 300, 42)
+	b, _ := SuggestCard(mHand, view, NO_CARD, 300, 42)
+	for i := range a.Wins {
+		if a.Wins[i] != b.Wins[i] {
+			t.Errorf("Expected same result with same seed, got %v and %v", a.Wins, b.Wins)
+		}
+	}
+
+	// last card, answering 5o
+	view = PlayView{
+		MPlayed:      []Card{{3, 'e'}, {2, 'b'}},
+		OPlayed:      []Card{{4, 'c'}, {1, 'b'}, {5, 'o'}},
+		OEnvido:      EnvidoAny(),
+		IsMHandFirst: false,
+	}
+	res, err = SuggestCard(mHand, view, NO_CARD, 10, 1)
+	if err != nil || res.Card != (Card{6, 'o'}) || res.Wins[0] != 1 {
+		t.Errorf("Expected to win playing 6o, got %v (%v)", res, err)
+	}
+}
+
+func TestSuggestCardErrors(t *testing.T) {
+	mHand := NewHand("3e 2b 6o")
+
+	// rival leads
+	view := PlayView{MPlayed: []Card{}, OPlayed: []Card{}, OEnvido: EnvidoAny(), IsMHandFirst: false}
+	if _, err := SuggestCard(mHand, view, NO_CARD, 10, 1); err == nil {
+		t.Errorf("Expected error playing out of turn")
+	}
+
+	// rival played 7b and 6b: can't have 20 of envido
+	view = PlayView{MPlayed: []Card{{3, 'e'}, {2, 'b'}}, OPlayed: []Card{{7, 'b'}, {6, 'b'}}, OEnvido: EnvidoExact(20), IsMHandFirst: false}
+	if _, err := SuggestCard(mHand, view, NO_CARD, 10, 1); err == nil {
+		t.Errorf("Expected error with an impossible rival hand")
+	}
+}
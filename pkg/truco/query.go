@@ -0,0 +1,466 @@
+package truco
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Hand query language, to explore the precomputed hand table (see CreateHandStatsCSV).
+//
+// A query is a boolean combination of conditions, eg. "has 1e and (envido >= 27 or flor)":
+//   - and, or, not, parenthesis: "not" binds tighter than "and", "and" tighter than "or"
+//   - has CARD: hand holds the card, eg. "has 7o"
+//   - flor: 3 cards of the same suit
+//   - card OP RANK: some card of the hand compares to the rank (see RANKS), eg. "card >= 7o"
+//   - high OP RANK, low OP RANK: highest or lowest card of the hand compares to the rank
+//   - envido OP N: envido of the hand (argentinian truco)
+//   - suit S OP N: amount of cards of suit S (e, b, o, c), eg. "suit e >= 2"
+//   - samesuit OP N: most cards of the same suit, eg. "samesuit >= 2" has envido
+//   - strength OP X: truco strength of the hand, 0-1
+//   - percentile OP X: % of hands weaker for truco than this one, 0-100
+//
+// Operators are =, !=, <, <=, >, >=.
+
+// A row of the hand table
+type HandRecord struct {
+	Hand       Hand    // cards sorted by truco strength
+	Strength   float64 // truco strength
+	Envido     uint8
+	Percentile float64 // % of hands with lower truco strength
+}
+
+// A parsed query: use ParseQuery
+type Query interface {
+	Matches(r HandRecord) bool
+	String() string
+}
+
+// Reads the hand table (output of CreateHandStatsCSV), and computes the percentile of each hand
+func LoadHandRecords(csvPath string) ([]HandRecord, error) {
+	rows, err := getCSVReader(csvPath)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]HandRecord, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 3 {
+			continue
+		}
+		strength, err := strconv.ParseFloat(row[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid strength '%s' for hand '%s'", row[1], row[0])
+		}
+		envido, err := strconv.ParseUint(row[2], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid envido '%s' for hand '%s'", row[2], row[0])
+		}
+		records = append(records, HandRecord{Hand: NewHand(row[0]), Strength: strength, Envido: uint8(envido)})
+	}
+
+	setPercentiles(records)
+	return records, nil
+}
+
+// Percentile of each record: % of records with a lower strength
+func setPercentiles(records []HandRecord) {
+	strengths := make([]float64, 0, len(records))
+	for _, r := range records {
+		strengths = append(strengths, r.Strength)
+	}
+	sort.Float64s(strengths)
+
+	for i := range records {
+		lower := sort.SearchFloat64s(strengths, records[i].Strength)
+		records[i].Percentile = 100 * float64(lower) / float64(len(records))
+	}
+}
+
+// Aggregate of the hands matching a query
+type QueryResult struct {
+	Hands        []HandRecord // matching hands, strongest first
+	Count        int          // amount of matching hands
+	Share        float64      // % of all hands that match
+	StrengthMean float64
+	EnvidoMean   float64
+	EnvidoShare  float64 // % of matching hands with envido (20+)
+	FlorShare    float64 // % of matching hands with flor
+}
+
+// Runs the query over records, keeping up to limit hands in the result (all if limit <= 0)
+func RunQuery(records []HandRecord, q Query, limit int) QueryResult {
+	res := QueryResult{Hands: make([]HandRecord, 0)}
+	var strengthSum, envidoSum float64
+	var envidos, flores int
+
+	for _, r := range records {
+		if !q.Matches(r) {
+			continue
+		}
+		res.Count++
+		strengthSum += r.Strength
+		envidoSum += float64(r.Envido)
+		if r.Envido >= 20 {
+			envidos++
+		}
+		if isFlor(r.Hand) {
+			flores++
+		}
+		res.Hands = append(res.Hands, r)
+	}
+
+	slices.SortStableFunc(res.Hands, func(a, b HandRecord) int {
+		if a.Strength > b.Strength {
+			return -1
+		} else if a.Strength < b.Strength {
+			return 1
+		}
+		return 0
+	})
+	if limit > 0 && len(res.Hands) > limit {
+		res.Hands = res.Hands[:limit]
+	}
+
+	if res.Count > 0 {
+		res.Share = 100 * float64(res.Count) / float64(len(records))
+		res.StrengthMean = strengthSum / float64(res.Count)
+		res.EnvidoMean = envidoSum / float64(res.Count)
+		res.EnvidoShare = 100 * float64(envidos) / float64(res.Count)
+		res.FlorShare = 100 * float64(flores) / float64(res.Count)
+	}
+	return res
+}
+
+func isFlor(h Hand) bool {
+	return maxSameSuit(h) == len(h)
+}
+
+func maxSameSuit(h Hand) int {
+	var best int
+	for _, c := range h {
+		best = max(best, suitCount(h, c.S))
+	}
+	return best
+}
+
+func suitCount(h Hand, s uint8) int {
+	var count int
+	for _, c := range h {
+		if c.S == s {
+			count++
+		}
+	}
+	return count
+}
+
+// ParseQuery parses a query of the hand query language.
+// Returns an error pointing to the offending token if the query is not valid.
+func ParseQuery(s string) (Query, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("Empty query")
+	}
+
+	p := queryParser{tokens: tokens}
+	q, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected '%s'", p.tokens[p.pos])
+	}
+	return q, nil
+}
+
+func lexQuery(s string) ([]string, error) {
+	tokens := make([]string, 0)
+	for i := 0; i < len(s); {
+		ch := rune(s[i])
+		switch {
+		case unicode.IsSpace(ch):
+			i++
+		case ch == '(' || ch == ')':
+			tokens = append(tokens, string(ch))
+			i++
+		case strings.ContainsRune("<>=!", ch):
+			if i+1 < len(s) && s[i+1] == '=' {
+				tokens = append(tokens, s[i:i+2])
+				i += 2
+			} else if ch == '!' {
+				return nil, fmt.Errorf("Unexpected '!', did you mean '!='?")
+			} else {
+				tokens = append(tokens, string(ch))
+				i++
+			}
+		case unicode.IsLetter(ch) || unicode.IsDigit(ch) || ch == '.':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, strings.ToLower(s[i:j]))
+			i = j
+		default:
+			return nil, fmt.Errorf("Unexpected '%c'", ch)
+		}
+	}
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) next() (string, error) {
+	if p.pos >= len(p.tokens) {
+		return "", fmt.Errorf("Unexpected end of query")
+	}
+	p.pos++
+	return p.tokens[p.pos-1], nil
+}
+
+func (p *queryParser) or() (Query, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "or" {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = orQuery{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) and() (Query, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "and" {
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = andQuery{left, right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) unary() (Query, error) {
+	switch p.peek() {
+	case "not":
+		p.pos++
+		q, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return notQuery{q}, nil
+	case "(":
+		p.pos++
+		q, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if tok, err := p.next(); err != nil || tok != ")" {
+			return nil, fmt.Errorf("Expected ')'")
+		}
+		return q, nil
+	}
+	return p.condition()
+}
+
+func (p *queryParser) condition() (Query, error) {
+	field, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch field {
+	case "flor":
+		return florQuery{}, nil
+
+	case "has":
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		c := NewCard(tok)
+		if !slices.Contains(ALL_CARDS, c) {
+			return nil, fmt.Errorf("Unknown card '%s'", tok)
+		}
+		return hasQuery{c}, nil
+
+	case "card", "high", "low":
+		op, err := p.operator()
+		if err != nil {
+			return nil, err
+		}
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		// piezas (rank > 14) only exist in uruguayan truco
+		rank, ok := RANKS[tok]
+		if !ok || rank > 14 {
+			return nil, fmt.Errorf("Unknown rank '%s'", tok)
+		}
+		return rankQuery{field: field, op: op, rank: tok}, nil
+
+	case "suit":
+		s, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains([]string{"e", "b", "o", "c"}, s) {
+			return nil, fmt.Errorf("Unknown suit '%s'", s)
+		}
+		op, value, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		return numberQuery{field: "suit " + s, op: op, value: value}, nil
+
+	case "envido", "samesuit", "strength", "percentile":
+		op, value, err := p.comparison()
+		if err != nil {
+			return nil, err
+		}
+		return numberQuery{field: field, op: op, value: value}, nil
+	}
+
+	if field == ")" || field == "and" || field == "or" {
+		return nil, fmt.Errorf("Unexpected '%s'", field)
+	}
+	return nil, fmt.Errorf("Unknown condition '%s'", field)
+}
+
+func (p *queryParser) operator() (string, error) {
+	op, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if !slices.Contains([]string{"=", "!=", "<", "<=", ">", ">="}, op) {
+		return "", fmt.Errorf("Expected an operator, got '%s'", op)
+	}
+	return op, nil
+}
+
+func (p *queryParser) comparison() (string, float64, error) {
+	op, err := p.operator()
+	if err != nil {
+		return "", 0, err
+	}
+	tok, err := p.next()
+	if err != nil {
+		return "", 0, err
+	}
+	value, err := strconv.ParseFloat(tok, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("Expected a number, got '%s'", tok)
+	}
+	return op, value, nil
+}
+
+func compare(a float64, op string, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+type andQuery struct{ left, right Query }
+type orQuery struct{ left, right Query }
+type notQuery struct{ q Query }
+type florQuery struct{}
+type hasQuery struct{ card Card }
+
+type rankQuery struct {
+	field string // card, high or low
+	op    string
+	rank  string
+}
+
+type numberQuery struct {
+	field string // envido, samesuit, strength, percentile or "suit S"
+	op    string
+	value float64
+}
+
+func (q andQuery) Matches(r HandRecord) bool  { return q.left.Matches(r) && q.right.Matches(r) }
+func (q orQuery) Matches(r HandRecord) bool   { return q.left.Matches(r) || q.right.Matches(r) }
+func (q notQuery) Matches(r HandRecord) bool  { return !q.q.Matches(r) }
+func (q florQuery) Matches(r HandRecord) bool { return isFlor(r.Hand) }
+func (q hasQuery) Matches(r HandRecord) bool  { return slices.Contains(r.Hand, q.card) }
+
+func (q rankQuery) Matches(r HandRecord) bool {
+	rank := float64(RANKS[q.rank])
+	ranks := make([]float64, 0, len(r.Hand))
+	for _, c := range r.Hand {
+		ranks = append(ranks, float64(c.Truco()))
+	}
+
+	switch q.field {
+	case "high":
+		return compare(slices.Max(ranks), q.op, rank)
+	case "low":
+		return compare(slices.Min(ranks), q.op, rank)
+	}
+	return slices.ContainsFunc(ranks, func(v float64) bool { return compare(v, q.op, rank) })
+}
+
+func (q numberQuery) Matches(r HandRecord) bool {
+	var v float64
+	switch q.field {
+	case "envido":
+		v = float64(r.Envido)
+	case "samesuit":
+		v = float64(maxSameSuit(r.Hand))
+	case "strength":
+		v = r.Strength
+	case "percentile":
+		v = r.Percentile
+	default:
+		v = float64(suitCount(r.Hand, strings.TrimPrefix(q.field, "suit ")[0]))
+	}
+	return compare(v, q.op, q.value)
+}
+
+func (q andQuery) String() string  { return "(" + q.left.String() + " and " + q.right.String() + ")" }
+func (q orQuery) String() string   { return "(" + q.left.String() + " or " + q.right.String() + ")" }
+func (q notQuery) String() string  { return "not " + q.q.String() }
+func (q florQuery) String() string { return "flor" }
+func (q hasQuery) String() string  { return "has " + q.card.ToString() }
+func (q rankQuery) String() string { return q.field + " " + q.op + " " + q.rank }
+func (q numberQuery) String() string {
+	return q.field + " " + q.op + " " + strconv.FormatFloat(q.value, 'f', -1, 64)
+}
@@ -0,0 +1,86 @@
+package truco
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{"has 1e", "has 1e"},
+		{"flor or envido>=27", "(flor or envido >= 27)"},
+		{"has 1e and envido >= 27 or flor", "((has 1e and envido >= 27) or flor)"},
+		{"has 1e and (envido >= 27 or flor)", "(has 1e and (envido >= 27 or flor))"},
+		{"not has 1e and card >= 7o", "(not has 1e and card >= 7o)"},
+		{"HIGH < 3 and suit e = 2", "(high < 3 and suit e = 2)"},
+		{"percentile >= 90.5", "percentile >= 90.5"},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Errorf("Unexpected error parsing '%s': %v", tt.query, err)
+		} else if q.String() != tt.expected {
+			t.Errorf("Expected '%s' to parse as '%s', got '%s'", tt.query, tt.expected, q)
+		}
+	}
+
+	for _, s := range []string{"", "has", "has 8e", "card >= 2p", "envido 27", "envido >= x", "(flor", "flor)", "flor flor", "suit x > 1", "envido ! 2", "foo"} {
+		if _, err := ParseQuery(s); err == nil {
+			t.Errorf("Expected error parsing '%s'", s)
+		}
+	}
+}
+
+func TestRunQuery(t *testing.T) {
+	records := []HandRecord{
+		{Hand: NewHand("1e 7o 3e"), Strength: 0.9, Envido: 24},
+		{Hand: NewHand("7e 6e 5e"), Strength: 0.7, Envido: 33},
+		{Hand: NewHand("3b 2o 1c"), Strength: 0.5, Envido: 3},
+		{Hand: NewHand("12o 11b 4c"), Strength: 0.1, Envido: 4},
+	}
+	setPercentiles(records)
+	if records[0].Percentile != 75 || records[3].Percentile != 0 {
+		t.Errorf("Expected percentiles 75 and 0, got %f and %f", records[0].Percentile, records[3].Percentile)
+	}
+
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{"flor", []string{"7e 6e 5e"}},
+		{"samesuit >= 2", []string{"1e 7o 3e", "7e 6e 5e"}},
+		{"card >= 7o", []string{"1e 7o 3e", "7e 6e 5e"}},
+		{"high < 7o", []string{"3b 2o 1c", "12o 11b 4c"}},
+		{"low >= 3", []string{"1e 7o 3e"}},
+		{"suit e = 1 or suit c >= 1", []string{"3b 2o 1c", "12o 11b 4c"}},
+		{"not has 1e and percentile >= 25", []string{"7e 6e 5e", "3b 2o 1c"}},
+		{"envido >= 20 and strength < 0.8", []string{"7e 6e 5e"}},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.query)
+		if err != nil {
+			t.Fatalf("Unexpected error parsing '%s': %v", tt.query, err)
+		}
+		res := RunQuery(records, q, 0)
+		if res.Count != len(tt.expected) {
+			t.Errorf("Expected %d hands for '%s', got %d", len(tt.expected), tt.query, res.Count)
+			continue
+		}
+		for i, r := range res.Hands {
+			if strings.TrimSpace(r.Hand.ToString()) != tt.expected[i] {
+				t.Errorf("Expected hand %d of '%s' to be %s, got %s", i, tt.query, tt.expected[i], r.Hand.ToString())
+			}
+		}
+	}
+
+	q, _ := ParseQuery("samesuit >= 2")
+	res := RunQuery(records, q, 1)
+	if res.Count != 2 || len(res.Hands) != 1 || res.Share != 50 || res.FlorShare != 50 || res.EnvidoMean != 28.5 {
+		t.Errorf("Unexpected aggregate %+v", res)
+	}
+}
@@ -69,29 +69,66 @@ func cardRangeNoEnvido(aCards, mCards []Card) []Hand {
 // - if len(mCards) == 3, then len(hands) == 1
 // - as len(kCards) grows, len(hands) shrinks
 // - len(hands) is not homogeneous over all envido scores
-func CardRange(score uint8, mCards, kCards []Card) []Hand {
+func CardRange(score EnvidoConstraint, mCards, kCards []Card) []Hand {
 	aCards := CardsExcluding(ALL_CARDS, kCards)
 	hands_ := cardRangeNoEnvido(aCards, mCards)
+	if score.IsAny() {
+		return hands_
+	}
+
 	hands := make([]Hand, 0, len(hands_))
+	for _, h := range hands_ {
+		if score.Allows(h.Envido()) {
+			hands = append(hands, h)
+		}
+	}
+	return hands
+}
 
-	if score == 255 {
-		// unknown envido
+// Given a muestra and an envido score, cards player holds (mCards) and other known cards they dont (kCards),
+// whats a list of possible hands they could have. The muestra is a known card: no hand holds it.
+//
+// Same as CardRange, with envido values as Hand.EnvidoUY (flor is 220-247)
+func CardRangeUY(score EnvidoConstraint, mCards, kCards []Card, muestra Card) []Hand {
+	aCards := CardsExcluding(ALL_CARDS, append(slices.Clone(kCards), muestra))
+	hands_ := cardRangeNoEnvido(aCards, mCards)
+	if score.IsAny() {
 		return hands_
-	} else if score >= 100 {
-		// only know that envido <= (score-100)
-		score_ := score - 100
-		for _, h := range hands_ {
-			if h.Envido() <= score_ {
-				hands = append(hands, h)
-			}
+	}
+
+	hands := make([]Hand, 0, len(hands_))
+	for _, h := range hands_ {
+		if score.Allows(h.EnvidoUY(muestra)) {
+			hands = append(hands, h)
 		}
-		return hands
-	} else {
-		for _, h := range hands_ {
-			if h.Envido() == score {
-				hands = append(hands, h)
-			}
+	}
+	return hands
+}
+
+// Hands a player could have in the rules r, as CardRange:
+// dealt from the deck of r, with the envido of r
+func rulesRange(r Ruleset, score EnvidoConstraint, mCards, kCards []Card) []Hand {
+	hands_ := cardRangeNoEnvido(CardsExcluding(r.Deck(), kCards), mCards)
+	if score.IsAny() {
+		return hands_
+	}
+
+	hands := make([]Hand, 0, len(hands_))
+	for _, h := range hands_ {
+		if score.Allows(r.Envido(h)) {
+			hands = append(hands, h)
 		}
 	}
 	return hands
 }
+
+// Returns true if a player holding mCards can still have a hand that fits score,
+// without holding any of kCards. m: muestra, NO_CARD for argentinian truco
+func CanReachEnvido(score EnvidoConstraint, mCards, kCards []Card, m Card) bool {
+	return CanReachEnvidoRules(RulesFor(m), score, mCards, kCards)
+}
+
+// CanReachEnvido in the rules r, eg. of a tracked match
+func CanReachEnvidoRules(r Ruleset, score EnvidoConstraint, mCards, kCards []Card) bool {
+	return score.IsAny() || len(rulesRange(r, score, mCards, kCards)) > 0
+}
@@ -41,7 +41,7 @@ func TestCardRange(t *testing.T) {
 	mCards := []Card{{6, 'e'}}
 	kCards := []Card{{1, 'b'}}
 
-	hands := CardRange(33, mCards, kCards)
+	hands := CardRange(EnvidoExact(33), mCards, kCards)
 
 	for _, h := range hands {
 		if h.Envido() != 33 {
@@ -52,22 +52,75 @@ func TestCardRange(t *testing.T) {
 		t.Errorf("Expected count == 40")
 	}
 
-	hands = CardRange(27, []Card{{10, 'e'}, {4, 'e'}}, []Card{{10, 'o'}, {5, 'e'}})
+	hands = CardRange(EnvidoExact(27), []Card{{10, 'e'}, {4, 'e'}}, []Card{{10, 'o'}, {5, 'e'}})
 	unexpectedHand := Hand{{10, 'e'}, {4, 'e'}, {7, 'e'}}
 	for _, h := range hands {
 		if equalHands(h, unexpectedHand) {
 			t.Errorf("Did not expect hand %v (Envido != 27) to be present", unexpectedHand)
 		}
 	}
-	rangeHands := CardRange(127, []Card{{1, 'e'}}, []Card{})
+	rangeHands := CardRange(EnvidoAtMost(27), []Card{{1, 'e'}}, []Card{})
 	if !slices.ContainsFunc(rangeHands, func(h Hand) bool { return h.Envido() <= 27 }) {
 		t.Errorf("Expected hands to have at least 27 envido")
 	}
 
-	hands = CardRange(127, []Card{{10, 'c'}, {4, 'e'}}, []Card{{10, 'o'}, {5, 'e'}})
+	hands = CardRange(EnvidoAtMost(27), []Card{{10, 'c'}, {4, 'e'}}, []Card{{10, 'o'}, {5, 'e'}})
 	for _, h := range hands {
 		if h.Envido() > 27 {
 			t.Errorf("Expected hand %v to <= 27 envido", h)
 		}
 	}
 }
+
+func TestCardRangeUY(t *testing.T) {
+	mCards := []Card{{6, 'e'}}
+	kCards := []Card{{1, 'b'}}
+	m := Card{4, 'c'}
+
+	hands := CardRangeUY(EnvidoExact(33), mCards, kCards, m)
+	if len(hands) == 0 {
+		t.Errorf("Expected some hands with 33 envido")
+	}
+	for _, h := range hands {
+		if h.EnvidoUY(m) != 33 {
+			t.Errorf("Expected hand %v to have 33 envido", h)
+		}
+		if !slices.Contains(h, mCards[0]) || slices.Contains(h, kCards[0]) || slices.Contains(h, m) {
+			t.Errorf("Expected hand %v to have 6e, and not 1b nor the muestra", h)
+		}
+	}
+
+	// without piezas in play, same as argentinian truco but for flor
+	m = Card{4, 'o'}
+	kCards = []Card{{2, 'o'}, {5, 'o'}, {10, 'o'}, {11, 'o'}, {12, 'o'}}
+	hands = CardRangeUY(EnvidoExact(33), mCards, kCards, m)
+	var count int
+	for _, h := range CardRange(EnvidoExact(33), mCards, append(slices.Clone(kCards), m)) {
+		if h[0].S != h[1].S || h[1].S != h[2].S {
+			count++
+		}
+	}
+	if len(hands) != count {
+		t.Errorf("Expected %d hands as CardRange without flor, got %d", count, len(hands))
+	}
+
+	rangeHands := CardRangeUY(EnvidoAtMost(27), []Card{{1, 'e'}}, []Card{}, m)
+	if len(rangeHands) == 0 {
+		t.Errorf("Expected hands with at most 27 envido")
+	}
+	for _, h := range rangeHands {
+		if h.EnvidoUY(m) > 27 {
+			t.Errorf("Expected hand %v to <= 27 envido", h)
+		}
+	}
+
+	for _, h := range CardRangeUY(EnvidoFlor(), []Card{}, []Card{}, m) {
+		if e := h.EnvidoUY(m); e <= 200 || e == 255 {
+			t.Errorf("Expected hand %v to have flor", h)
+		}
+	}
+
+	if len(CardRangeUY(EnvidoAny(), []Card{}, []Card{}, m)) != 9139 {
+		t.Errorf("Expected every hand without the muestra")
+	}
+}
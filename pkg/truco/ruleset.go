@@ -0,0 +1,102 @@
+package truco
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Rules of a variant of truco.
+//
+// Code that takes a Ruleset works for every variant, with no branch per variant.
+// Older functions take the muestra instead (m == NO_CARD for argentinian truco):
+// RulesFor gives their ruleset.
+type Ruleset interface {
+	Name() string                // short name, eg. "AR"
+	Deck() []Card                // cards that can be dealt, in truco order
+	Muestra() Card               // card turned up that changes the ranking, NO_CARD if none
+	Rank(c Card) uint8           // truco rank of c: higher beats lower
+	Envido(h Hand) uint8         // envido of h, flor as 200+ if HasFlor. Doesn't change h
+	HasFlor() bool               // flor is declared and scored apart from envido
+	MaxEnvido() uint8            // highest envido, without flor
+	Bets() BetSchedule           // points of every bet
+	Beats(mHand, oHand Hand) int // 1 if mHand beats oHand playing cards in order, 0 otherwise
+}
+
+// Points of the bets of a variant
+type BetSchedule struct {
+	Truco      []uint8  // points of the hand for each truco bet: no bet, then every raise (truco, retruco, vale 4)
+	Raises     []string // name of each raise: Raises[i] asks for Truco[i+1]
+	Envido     uint8    // points of an envido, 0 if the variant has no envido
+	RealEnvido uint8    // points of a real envido
+	Eleven     bool     // a team at 11 points may play the hand for the second bet, or give away the first ('mão de onze')
+}
+
+// Bets of argentinian and uruguayan truco
+var BETS_AR = BetSchedule{Truco: []uint8{1, 2, 3, 4}, Raises: []string{"Truco", "Retruco", "Vale 4"}, Envido: 2, RealEnvido: 3}
+
+// Argentinian truco: 40 cards, no muestra, flor counts as envido
+type ArgentineRules struct{}
+
+func (ArgentineRules) Name() string        { return "AR" }
+func (ArgentineRules) Deck() []Card        { return ALL_CARDS }
+func (ArgentineRules) Muestra() Card       { return NO_CARD }
+func (ArgentineRules) Rank(c Card) uint8   { return GetTruco(c) }
+func (ArgentineRules) HasFlor() bool       { return false }
+func (ArgentineRules) MaxEnvido() uint8    { return MAX_ENVIDO_AR }
+func (ArgentineRules) Bets() BetSchedule   { return BETS_AR }
+func (ArgentineRules) Envido(h Hand) uint8 { return slices.Clone(h).Envido() }
+
+func (r ArgentineRules) Beats(mHand, oHand Hand) int {
+	return trucoBeatsRanks(handRanks(r, mHand), handRanks(r, oHand))
+}
+
+// Uruguayan truco: the muestra turns the cards of its suit into piezas, and flor is scored
+type UruguayanRules struct {
+	M Card // muestra
+}
+
+func (r UruguayanRules) Name() string        { return "UY" }
+func (r UruguayanRules) Deck() []Card        { return CardsExcluding(ALL_CARDS, []Card{r.M}) }
+func (r UruguayanRules) Muestra() Card       { return r.M }
+func (r UruguayanRules) Rank(c Card) uint8   { return GetTrucoUY(c, r.M) }
+func (r UruguayanRules) HasFlor() bool       { return true }
+func (r UruguayanRules) MaxEnvido() uint8    { return MAX_ENVIDO_UY }
+func (r UruguayanRules) Bets() BetSchedule   { return BETS_AR }
+func (r UruguayanRules) Envido(h Hand) uint8 { return h.EnvidoUY(r.M) }
+
+func (r UruguayanRules) Beats(mHand, oHand Hand) int {
+	return trucoBeatsRanks(handRanks(r, mHand), handRanks(r, oHand))
+}
+
+// Returns the ruleset of the older functions that take a muestra:
+// argentinian truco for NO_CARD, uruguayan truco otherwise
+func RulesFor(m Card) Ruleset {
+	if m == NO_CARD {
+		return ArgentineRules{}
+	}
+	return UruguayanRules{M: m}
+}
+
+// Returns the ruleset named name (see Ruleset.Name), with muestra m if it has one
+func NewRuleset(name string, m Card) (Ruleset, error) {
+	switch name {
+	case "AR":
+		return ArgentineRules{}, nil
+	case "UY":
+		if !slices.Contains(ALL_CARDS, m) {
+			return nil, fmt.Errorf("Uruguayan truco needs a muestra")
+		}
+		return UruguayanRules{M: m}, nil
+	case "BR":
+		if !slices.Contains(ALL_CARDS, m) {
+			return nil, fmt.Errorf("Truco paulista needs a vira")
+		}
+		return PaulistaRules{V: m}, nil
+	}
+	return nil, fmt.Errorf("Unknown ruleset %s", name)
+}
+
+// Ranks of the cards of a hand, in order
+func handRanks(r Ruleset, h Hand) [3]uint8 {
+	return [3]uint8{r.Rank(h[0]), r.Rank(h[1]), r.Rank(h[2])}
+}
@@ -0,0 +1,67 @@
+package truco
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRulesets(t *testing.T) {
+	m := Card{N: 4, S: 'c'}
+	ar, uy := ArgentineRules{}, UruguayanRules{M: m}
+
+	for _, c := range ALL_CARDS {
+		if ar.Rank(c) != c.Truco() {
+			t.Errorf("AR: rank of %s should be %d, got %d", c.ToString(), c.Truco(), ar.Rank(c))
+		}
+		if uy.Rank(c) != c.TrucoUY(m) {
+			t.Errorf("UY: rank of %s should be %d, got %d", c.ToString(), c.TrucoUY(m), uy.Rank(c))
+		}
+	}
+
+	if len(ar.Deck()) != 40 || len(uy.Deck()) != 39 || slices.Contains(uy.Deck(), m) {
+		t.Errorf("expected 40 cards in AR and 39 without the muestra in UY, got %d and %d", len(ar.Deck()), len(uy.Deck()))
+	}
+
+	hands := []Hand{NewHand("1e 7o 3c"), NewHand("4c 5c 12c"), NewHand("2b 11b 6o"), NewHand("10c 7e 1b")}
+	for _, mHand := range hands {
+		for _, oHand := range hands {
+			if ar.Beats(mHand, oHand) != TrucoBeats(mHand, oHand, NO_CARD) {
+				t.Errorf("AR: %s vs %s doesn't match TrucoBeats", mHand.ToString(), oHand.ToString())
+			}
+			if uy.Beats(mHand, oHand) != TrucoBeats(mHand, oHand, m) {
+				t.Errorf("UY: %s vs %s doesn't match TrucoBeats", mHand.ToString(), oHand.ToString())
+			}
+		}
+	}
+
+	// flor counts as envido in AR, and is scored apart in UY
+	hand := NewHand("5c 7c 12c")
+	if e := ar.Envido(hand); e != 32 {
+		t.Errorf("AR: expected envido 32, got %d", e)
+	}
+	if e := uy.Envido(hand); e < 200 {
+		t.Errorf("UY: expected flor, got %d", e)
+	}
+	if hand[0] != (Card{N: 5, S: 'c'}) {
+		t.Errorf("Envido should not sort the hand, got %s", hand.ToString())
+	}
+}
+
+func TestNewRuleset(t *testing.T) {
+	m := Card{N: 4, S: 'c'}
+	if r, err := NewRuleset("UY", m); err != nil || r != (UruguayanRules{M: m}) {
+		t.Errorf("expected uruguayan rules, got %v, %v", r, err)
+	}
+	if r, err := NewRuleset("AR", NO_CARD); err != nil || r != (ArgentineRules{}) {
+		t.Errorf("expected argentinian rules, got %v, %v", r, err)
+	}
+	if _, err := NewRuleset("UY", NO_CARD); err == nil {
+		t.Errorf("expected error without a muestra")
+	}
+	if _, err := NewRuleset("XX", NO_CARD); err == nil {
+		t.Errorf("expected error with an unknown ruleset")
+	}
+	if RulesFor(NO_CARD) != (ArgentineRules{}) || RulesFor(m) != (UruguayanRules{M: m}) {
+		t.Errorf("RulesFor doesn't match the muestra")
+	}
+}
@@ -28,6 +28,7 @@ type PairStat struct {
 	EnvidoMedian float64 `json:"envido_median"`
 	CombinedMean float64 `json:"combined_mean"`
 	Count        int     `json:"count"`
+	DealP        float64 `json:"deal_p"` // chance of being dealt the pair, given the known cards and the cards played (see DealOdds.PairHolding)
 }
 
 type StatsKey struct {
@@ -44,7 +45,22 @@ type FilterHands struct {
 	KCards  []Card
 	MCards  []Card
 	KEnvido []uint8 // TODO maybe unnecesary
-	MEnvido uint8
+	MEnvido EnvidoConstraint
+}
+
+// Csv of hand stats of each ruleset, by name of the rules (see CreateHandStatsCSV).
+// Rules not listed use the argentinian one
+var HAND_STATS_CSV = map[string]string{
+	"AR": "web/static/hand_stats.csv",
+	"BR": "web/static/hand_stats_br.csv",
+}
+
+// Returns the path of the csv of hand stats for r (see HAND_STATS_CSV)
+func HandStatsCSV(r Ruleset) string {
+	if path, ok := HAND_STATS_CSV[r.Name()]; ok {
+		return path
+	}
+	return HAND_STATS_CSV["AR"]
 }
 
 // Creates a csv file that lists all possible hands with:
@@ -54,78 +70,75 @@ type FilterHands struct {
 //
 // Hands are sorted by strength.
 // Cards in each hand are sorted by truco strength.
+//
+// Truco strength only depends on the truco ranks of the hand,
+// so we compute it once per class of hands (see TrucoClasses).
 func CreateHandStatsCSV(outputPath string) error {
-	handsIter := math.Combinations(ALL_CARDS, 3)
+	classes := TrucoClasses(ALL_CARDS, NO_CARD)
+	classHands := make([]Hand, len(classes))
+	for i, c := range classes {
+		classHands[i] = c.Hand
+	}
 
-	type job struct {
-		hand Hand
+	strengths := make(map[[3]uint8]float32)
+	for i, strength := range parallelStrengths(classHands, Hand.TrucoStrength) {
+		strengths[trucoKey(classHands[i])] = strength
 	}
-	type result struct {
-		handStr  string
-		strength float32
-		envido   uint8
-		combined float32
+
+	var rows []handStatsRow
+	for h := range math.Combinations(ALL_CARDS, 3) {
+		hand := Hand(h)
+		// Cards in each hand are sorted by truco strength.
+		slices.SortFunc(hand, SortForTruco)
+
+		strength := strengths[trucoKey(hand)]
+		envido := slices.Clone(hand).Envido()
+		rows = append(rows, handStatsRow{
+			hand:     hand,
+			strength: strength,
+			envido:   envido,
+			combined: (float32(envido)/MAX_ENVIDO_AR + strength) / 2.0,
+		})
 	}
+	return writeHandStatsCSV(outputPath, rows)
+}
 
-	numWorkers := runtime.NumCPU()
-	jobs := make(chan job, 100)
-	resultsChan := make(chan result, 100)
+// A row of the csv of hand stats
+type handStatsRow struct {
+	hand     Hand
+	strength float32
+	envido   uint8
+	combined float32
+}
+
+// Computes strength of every hand, in parallel.
+// Returns the strengths in the order of hands.
+func parallelStrengths(hands []Hand, strength func(Hand) float32) []float32 {
+	strengths := make([]float32, len(hands))
+	jobs := make(chan int, 100)
 	var wg sync.WaitGroup
 
-	for range numWorkers {
+	for range runtime.NumCPU() {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
-			for j := range jobs {
-				h := j.hand
-				// Cards in each hand are sorted by truco strength.
-				slices.SortFunc(h, SortForTruco)
-
-				var handStr strings.Builder
-				for i, c := range h {
-					handStr.WriteString(c.ToString())
-					if i < len(h)-1 {
-						handStr.WriteString(" ")
-					}
-				}
-
-				strength := h.TrucoStrength()
-				envido := h.Envido()
-				combined := (float32(envido)/MAX_ENVIDO_AR + strength) / 2.0
-
-				resultsChan <- result{
-					handStr:  handStr.String(),
-					strength: strength,
-					envido:   envido,
-					combined: combined,
-				}
+			for i := range jobs {
+				strengths[i] = strength(hands[i])
 			}
 		}()
 	}
 
-	// Feed jobs
-	go func() {
-		for h := range handsIter {
-			// math.Combinations yields a slice that we should clone if we were to reuse it,
-			// but its implementation already clones it.
-			jobs <- job{hand: Hand(h)}
-		}
-		close(jobs)
-	}()
-
-	// Close results channel when workers are done
-	go func() {
-		wg.Wait()
-		close(resultsChan)
-	}()
-
-	var allResults []result
-	for r := range resultsChan {
-		allResults = append(allResults, r)
+	for i := range hands {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
+	return strengths
+}
 
-	// Hands are sorted by strength (descending).
-	slices.SortFunc(allResults, func(a, b result) int {
+// Writes the csv of hand stats, sorted by strength (descending)
+func writeHandStatsCSV(outputPath string, rows []handStatsRow) error {
+	slices.SortStableFunc(rows, func(a, b handStatsRow) int {
 		if b.strength > a.strength {
 			return 1
 		} else if b.strength < a.strength {
@@ -134,7 +147,6 @@ func CreateHandStatsCSV(outputPath string) error {
 		return 0
 	})
 
-	// Save to CSV
 	f, err := os.Create(outputPath)
 	if err != nil {
 		return err
@@ -147,9 +159,9 @@ func CreateHandStatsCSV(outputPath string) error {
 	// Header
 	writer.Write([]string{"hand", "strength", "envido", "combined"})
 
-	for _, r := range allResults {
+	for _, r := range rows {
 		writer.Write([]string{
-			r.handStr,
+			strings.TrimSpace(r.hand.ToString()),
 			fmt.Sprintf("%.6f", r.strength),
 			fmt.Sprintf("%d", r.envido),
 			fmt.Sprintf("%.6f", r.combined),
@@ -159,6 +171,14 @@ func CreateHandStatsCSV(outputPath string) error {
 	return nil
 }
 
+// Truco ranks of a hand, highest first: same key, same truco strength
+func trucoKey(hand Hand) [3]uint8 {
+	key := [3]uint8{hand[0].Truco(), hand[1].Truco(), hand[2].Truco()}
+	slices.Sort(key[:])
+	slices.Reverse(key[:])
+	return key
+}
+
 func getCSVReader(csvPath string) ([][]string, error) {
 	f, err := os.Open(csvPath)
 	if err != nil {
@@ -185,7 +205,7 @@ func getCSVReader(csvPath string) ([][]string, error) {
 }
 
 func filterRecords(records *[][]string, filter FilterHands) *[][]string {
-	if len(filter.KCards) == 0 && len(filter.MCards) == 0 && filter.MEnvido == 255 {
+	if len(filter.KCards) == 0 && len(filter.MCards) == 0 && filter.MEnvido.IsAny() {
 		return records
 	}
 
@@ -230,17 +250,8 @@ func filterRecords(records *[][]string, filter FilterHands) *[][]string {
 			continue
 		}
 
-		if filter.MEnvido < 100 {
-			// MEnvido declared exactly
-			if NewHand(handStr).Envido() != filter.MEnvido {
-				continue
-			}
-		} else if filter.MEnvido != 255 {
-			// MEnvido declared at a range (eg. 127: '27 son buenas')
-			// This means my hand is worse than or equal to 27.
-			if NewHand(handStr).Envido() > filter.MEnvido-100 {
-				continue
-			}
+		if !filter.MEnvido.IsAny() && !filter.MEnvido.Allows(NewHand(handStr).Envido()) {
+			continue
 		}
 
 		fRecords = append(fRecords, row)
@@ -309,6 +320,7 @@ func ComputePairStats(withEnvido bool, filter FilterHands) (map[string]PairStat,
 	}
 
 	statsResult := make(map[string]PairStat)
+	odds := NewDealOdds(filter.KCards)
 
 	// Compute metrics for each pair
 	for key, d := range statsMapInternal {
@@ -334,6 +346,12 @@ func ComputePairStats(withEnvido bool, filter FilterHands) (map[string]PairStat,
 
 		meanC := (meanT + meanE/MAX_ENVIDO_AR) / 2
 
+		ranks := strings.Split(key.pair, " ")
+		dealP := odds.PairHolding(ranks[0], ranks[1], key.isEnvido, filter.MCards)
+		if !withEnvido {
+			dealP += odds.PairHolding(ranks[0], ranks[1], true, filter.MCards)
+		}
+
 		stat := PairStat{
 			Pair:         key.pair,
 			IsEnvido:     key.isEnvido,
@@ -347,6 +365,7 @@ func ComputePairStats(withEnvido bool, filter FilterHands) (map[string]PairStat,
 			EnvidoMedian: float64(medianE),
 			CombinedMean: meanC,
 			Count:        count,
+			DealP:        dealP,
 		}
 
 		// Return key matches frontend expectations: "rank1 rank2 bool"
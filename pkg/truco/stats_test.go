@@ -23,7 +23,7 @@ func TestFilterRecords(t *testing.T) {
 			name: "Filter KCards - exclude 1e",
 			filter: FilterHands{
 				KCards:  []Card{{1, 'e'}},
-				MEnvido: 255,
+				MEnvido: EnvidoAny(),
 			},
 			expected: [][]string{
 				{"1b 2b 3c", "0.5", "23"},
@@ -36,7 +36,7 @@ func TestFilterRecords(t *testing.T) {
 			filter: FilterHands{
 				KCards:  []Card{{4, 'b'}},
 				MCards:  []Card{{1, 'e'}},
-				MEnvido: 255,
+				MEnvido: EnvidoAny(),
 			},
 			expected: [][]string{
 				{"1e 2e 3e", "1.0", "25"},
@@ -47,7 +47,7 @@ func TestFilterRecords(t *testing.T) {
 			filter: FilterHands{
 				KCards:  []Card{},
 				MCards:  []Card{},
-				MEnvido: 27,
+				MEnvido: EnvidoExact(27),
 			},
 			expected: [][]string{
 				{"7b 10b 11b", "0.2", "27"},
@@ -58,15 +58,15 @@ func TestFilterRecords(t *testing.T) {
 			filter: FilterHands{
 				KCards:  []Card{{7, 'e'}}, // Exclude hand 0
 				MCards:  []Card{{1, 'e'}}, // Must have 1e (Hands 0, 1)
-				MEnvido: 255,
+				MEnvido: EnvidoAny(),
 			},
 			expected: [][]string{
 				{"1e 2e 3e", "1.0", "25"},
 			},
 		},
 		{
-			name:   "Filter MEnvido range (son buenas) - eg 27 son buenas (127)",
-			filter: FilterHands{MEnvido: 127}, // 27 son buenas
+			name:   "Filter MEnvido range (son buenas) - eg 27 son buenas",
+			filter: FilterHands{MEnvido: EnvidoAtMost(27)},
 			expected: [][]string{
 				{"1e 2e 3e", "1.0", "25"},   // Envido 25 <= 27
 				{"1b 2b 3c", "0.5", "23"},   // Envido 23 <= 27
@@ -78,7 +78,7 @@ func TestFilterRecords(t *testing.T) {
 			filter: FilterHands{
 				KCards:  []Card{{4, 'b'}},
 				MCards:  []Card{{1, 'e'}},
-				MEnvido: 124,
+				MEnvido: EnvidoAtMost(24),
 			},
 			expected: [][]string{},
 		},
@@ -114,7 +114,7 @@ func TestFilterRecordsMoreCases(t *testing.T) {
 			filter: FilterHands{
 				KCards:  []Card{{11, 'b'}},
 				MCards:  []Card{{3, 'c'}},
-				MEnvido: 130,
+				MEnvido: EnvidoAtMost(30),
 			},
 			expected: [][]string{
 				{"1b 2b 3c", "0.5", "23"},
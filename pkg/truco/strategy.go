@@ -0,0 +1,223 @@
+package truco
+
+import (
+	"slices"
+	"truco/pkg/math"
+)
+
+// Adaptive card play: instead of committing to one of the 6 orders of mHand,
+// every card is chosen after seeing what the opponent showed so far.
+//
+// The opponent plays every order of its hand with the same chance (as UniformOpponent):
+// it doesn't adapt to our cards. The strategy maximizes the hands we win against it.
+//
+// The strategy is a decision tree:
+//   - StrategyNode: a card we play
+//   - StrategyBranch: what the opponent shows before our next decision
+//
+// Same trick rules as Minimax.
+
+// Decision of the strategy: the card to play, and what can happen after playing it
+type StrategyNode struct {
+	Card     Card             // card to play
+	Wins     float32          // win rate playing Card and then following Branches
+	Branches []StrategyBranch // one per outcome that can be observed after playing Card
+}
+
+// Outcome observed by mHand, leading to the next decision
+type StrategyBranch struct {
+	Seen  []Card        // opponent cards shown before the next decision, in order
+	Count int           // opponent orders that reach this branch
+	Wins  float32       // win rate from this branch on
+	Next  *StrategyNode // next decision, nil if the hand is over
+}
+
+// Result of AdaptiveStrategy
+type StrategyStats struct {
+	MHand        []string         // my hand: input parameter
+	WinRate      float32          // win rate of the adaptive strategy
+	FixedWinRate float32          // win rate of the best fixed order, against the same opponent
+	FixedPerm    Hand             // best fixed order
+	Count        int              // amount of opponent orders simulated
+	Root         []StrategyBranch // before the first decision: one branch per card the opponent may lead with
+}
+
+// Strategy finds the best adaptive way to play mHand.
+// Parameters as in StrengthStats.
+func (mHand Hand) Strategy(r Ruleset, kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool) StrategyStats {
+	return adaptiveStrategy(r, mHand, possibleOHands(r, mHand, kCards, oCards, envido), kCards, isMHandFirst)
+}
+
+// AdaptiveStrategy is Strategy for argentinian truco.
+func (mHand Hand) AdaptiveStrategy(kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool) StrategyStats {
+	return mHand.Strategy(ArgentineRules{}, kCards, oCards, envido, isMHandFirst)
+}
+
+// AdaptiveStrategyUY is Strategy for uruguayan truco.
+// First card of oCards is 'muestra'.
+func (mHand Hand) AdaptiveStrategyUY(kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool) StrategyStats {
+	return mHand.Strategy(UruguayanRules{M: oCards[0]}, kCards, oCards[1:], envido, isMHandFirst)
+}
+
+func adaptiveStrategy(r Ruleset, mHand Hand, oHands []Hand, kCards []Card, isMHandFirst bool) StrategyStats {
+	sMHand := make([]string, 0, len(mHand))
+	for _, c := range mHand {
+		sMHand = append(sMHand, c.ToEmoji())
+	}
+	stats := StrategyStats{MHand: sMHand}
+
+	oPerms := make([]Hand, 0, len(oHands)*6)
+	for _, oHand := range oHands {
+		for _, oPerm := range math.PermutationsRaw(oHand, 3) {
+			if Hand(oPerm).HasAllInPlace(kCards) {
+				oPerms = append(oPerms, oPerm)
+			}
+		}
+	}
+	stats.Count = len(oPerms)
+	if stats.Count == 0 {
+		return stats
+	}
+
+	s := strategySearch{solver{r: r}}
+	branches, wins := s.next(mHand, oPerms, []int{}, isMHandFirst, []Card{})
+	stats.Root = branches
+	stats.WinRate = float32(wins) / float32(stats.Count)
+
+	bestFixed := -1
+	for _, mPerm := range math.PermutationsRaw(mHand, 3) {
+		var fixed int
+		for _, oPerm := range oPerms {
+			fixed += r.Beats(mPerm, oPerm)
+		}
+		if fixed > bestFixed {
+			bestFixed = fixed
+			stats.FixedPerm = mPerm
+		}
+	}
+	stats.FixedWinRate = float32(bestFixed) / float32(stats.Count)
+
+	return stats
+}
+
+type strategySearch struct {
+	solver
+}
+
+// Continues the hand after the tricks in results, up to the next decision of mHand.
+// Every order in oPerms is consistent with what mHand has seen so far.
+//
+// Returns the branches mHand may observe, and the hands won following them.
+func (s strategySearch) next(mRem []Card, oPerms []Hand, results []int, mLeads bool, seen []Card) ([]StrategyBranch, int) {
+	if res, done := trucoResult(results); done {
+		wins := resultValue(res) * len(oPerms)
+		return []StrategyBranch{s.branch(seen, oPerms, wins, nil)}, wins
+	}
+
+	if mLeads {
+		node, wins := s.decide(mRem, oPerms, results, true)
+		return []StrategyBranch{s.branch(seen, oPerms, wins, node)}, wins
+	}
+
+	// the opponent leads: we see its card before deciding
+	var wins int
+	branches := make([]StrategyBranch, 0)
+	for _, group := range s.group(oPerms, len(results)) {
+		oCard := group[0][len(results)]
+		node, w := s.decide(mRem, group, results, false)
+		branches = append(branches, s.branch(append(slices.Clone(seen), oCard), group, w, node))
+		wins += w
+	}
+	return branches, wins
+}
+
+// Chooses the card of mHand for the current trick.
+// If the opponent leads, its card is already known: the same in every order of oPerms.
+//
+// Returns the best decision, and the hands won with it.
+func (s strategySearch) decide(mRem []Card, oPerms []Hand, results []int, mLeads bool) (*StrategyNode, int) {
+	trick := len(results)
+	// lowest cards first: on equal value we keep the cheapest card
+	cards := slices.Clone(mRem)
+	slices.SortStableFunc(cards, func(a, b Card) int {
+		return int(s.r.Rank(a)) - int(s.r.Rank(b))
+	})
+
+	var best *StrategyNode
+	bestWins := -1
+	for _, c := range cards {
+		nextM := CardsExcluding(mRem, []Card{c})
+		branches := make([]StrategyBranch, 0)
+		var wins int
+
+		if mLeads {
+			// the opponent answers: we see its card before the next decision
+			for _, group := range s.group(oPerms, trick) {
+				oCard := group[0][trick]
+				b, w := s.afterTrick(c, oCard, nextM, group, results, mLeads, []Card{oCard})
+				branches = append(branches, b...)
+				wins += w
+			}
+		} else {
+			oCard := oPerms[0][trick]
+			b, w := s.afterTrick(c, oCard, nextM, oPerms, results, mLeads, []Card{})
+			branches = append(branches, b...)
+			wins += w
+		}
+
+		if wins > bestWins {
+			bestWins = wins
+			best = &StrategyNode{
+				Card:     c,
+				Wins:     float32(wins) / float32(len(oPerms)),
+				Branches: branches,
+			}
+		}
+	}
+	return best, bestWins
+}
+
+// Settles the trick between mCard and oCard, and continues the hand
+func (s strategySearch) afterTrick(mCard, oCard Card, mRem []Card, oPerms []Hand, results []int, mLeads bool, seen []Card) ([]StrategyBranch, int) {
+	res := s.trick(mCard, oCard)
+	if res == 1 {
+		mLeads = true
+	} else if res == -1 {
+		mLeads = false
+	}
+	return s.next(mRem, oPerms, append(slices.Clone(results), res), mLeads, seen)
+}
+
+// Groups the orders in oPerms by the card played at trick, lowest rank first
+func (s strategySearch) group(oPerms []Hand, trick int) [][]Hand {
+	groups := make(map[Card][]Hand)
+	for _, oPerm := range oPerms {
+		groups[oPerm[trick]] = append(groups[oPerm[trick]], oPerm)
+	}
+
+	keys := make([]Card, 0, len(groups))
+	for c := range groups {
+		keys = append(keys, c)
+	}
+	slices.SortFunc(keys, func(a, b Card) int {
+		if r := int(s.r.Rank(a)) - int(s.r.Rank(b)); r != 0 {
+			return r
+		}
+		return cardIndex(a) - cardIndex(b)
+	})
+
+	res := make([][]Hand, 0, len(keys))
+	for _, c := range keys {
+		res = append(res, groups[c])
+	}
+	return res
+}
+
+func (s strategySearch) branch(seen []Card, oPerms []Hand, wins int, node *StrategyNode) StrategyBranch {
+	return StrategyBranch{
+		Seen:  seen,
+		Count: len(oPerms),
+		Wins:  float32(wins) / float32(len(oPerms)),
+		Next:  node,
+	}
+}
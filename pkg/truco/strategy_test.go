@@ -0,0 +1,86 @@
+package truco
+
+import (
+	"slices"
+	"testing"
+	"truco/pkg/math"
+)
+
+// Plays the strategy against a fixed order of the opponent.
+// Returns the cards played by mHand, in order.
+func followStrategy(branches []StrategyBranch, oPerm Hand) (Hand, bool) {
+	mOrder := make(Hand, 0, 3)
+	seen := make([]Card, 0, 3)
+	for {
+		var next *StrategyBranch
+		for i, b := range branches {
+			if slices.Equal(b.Seen, oPerm[len(seen):len(seen)+len(b.Seen)]) {
+				next = &branches[i]
+				break
+			}
+		}
+		if next == nil {
+			return nil, false
+		}
+		seen = append(seen, next.Seen...)
+		if next.Next == nil {
+			return mOrder, true
+		}
+		mOrder = append(mOrder, next.Next.Card)
+		branches = next.Next.Branches
+	}
+}
+
+func TestAdaptiveStrategy(t *testing.T) {
+	for _, hs := range []string{"7e 12c 2o", "1b 6o 6c", "4e 5b 3o"} {
+		mHand := NewHand(hs)
+		for _, isMHandFirst := range []bool{true, false} {
+			stats := mHand.AdaptiveStrategy([]Card{}, []Card{}, EnvidoAny(), isMHandFirst)
+
+			if stats.Count != 6*7770 {
+				t.Errorf("Hand %s: expected every opponent order, got %d", hs, stats.Count)
+			}
+			if stats.WinRate < stats.FixedWinRate {
+				t.Errorf("Hand %s: adaptive %f should be >= fixed %f", hs, stats.WinRate, stats.FixedWinRate)
+			}
+
+			var count int
+			for _, b := range stats.Root {
+				count += b.Count
+			}
+			if count != stats.Count {
+				t.Errorf("Hand %s: root branches should cover every order, got %d", hs, count)
+			}
+		}
+	}
+}
+
+// Following the tree against every opponent order must give the win rate of the strategy
+func TestAdaptiveStrategyFollow(t *testing.T) {
+	mHand := NewHand("1e 7o 3c")
+	kCards := []Card{{4, 'b'}}
+	stats := mHand.AdaptiveStrategy(kCards, []Card{}, EnvidoAny(), false)
+
+	var wins, count int
+	for _, oHand := range possibleOHands(ArgentineRules{}, mHand, kCards, []Card{}, EnvidoAny()) {
+		for _, oPerm := range math.PermutationsRaw(oHand, 3) {
+			if !Hand(oPerm).HasAllInPlace(kCards) {
+				continue
+			}
+			mOrder, ok := followStrategy(stats.Root, oPerm)
+			if !ok {
+				t.Fatalf("No branch for opponent order %v", oPerm)
+			}
+			mOrder = append(mOrder, CardsExcluding(mHand, mOrder)...)
+			wins += TrucoBeats(mOrder, oPerm, NO_CARD)
+			count++
+		}
+	}
+
+	if count != stats.Count {
+		t.Errorf("Expected %d orders, got %d", stats.Count, count)
+	}
+	if got := float32(wins) / float32(count); got != stats.WinRate {
+		t.Errorf("Expected win rate %f following the tree, got %f", stats.WinRate, got)
+	}
+}
@@ -2,6 +2,7 @@ package truco
 
 import (
 	"fmt"
+	"slices"
 	"truco/pkg/math"
 )
 
@@ -12,16 +13,23 @@ import (
 //   - 1 if mHand beats oHand
 //   - 0 if there's a tie or loss
 func TrucoBeats(mHand, oHand Hand, m Card) int {
-	var s0, s1, s2 int
-	var o0, o1, o2, m0, m1, m2 uint8
-
 	if m == NO_CARD {
-		o0, o1, o2 = oHand[0].Truco(), oHand[1].Truco(), oHand[2].Truco()
-		m0, m1, m2 = mHand[0].Truco(), mHand[1].Truco(), mHand[2].Truco()
-	} else {
-		o0, o1, o2 = oHand[0].TrucoUY(m), oHand[1].TrucoUY(m), oHand[2].TrucoUY(m)
-		m0, m1, m2 = mHand[0].TrucoUY(m), mHand[1].TrucoUY(m), mHand[2].TrucoUY(m)
+		return trucoBeatsRanks(
+			[3]uint8{mHand[0].Truco(), mHand[1].Truco(), mHand[2].Truco()},
+			[3]uint8{oHand[0].Truco(), oHand[1].Truco(), oHand[2].Truco()},
+		)
 	}
+	return trucoBeatsRanks(
+		[3]uint8{mHand[0].TrucoUY(m), mHand[1].TrucoUY(m), mHand[2].TrucoUY(m)},
+		[3]uint8{oHand[0].TrucoUY(m), oHand[1].TrucoUY(m), oHand[2].TrucoUY(m)},
+	)
+}
+
+// TrucoBeats, from the truco ranks of the cards of each hand
+func trucoBeatsRanks(mRanks, oRanks [3]uint8) int {
+	var s0, s1, s2 int
+	m0, m1, m2 := mRanks[0], mRanks[1], mRanks[2]
+	o0, o1, o2 := oRanks[0], oRanks[1], oRanks[2]
 
 	if m0 > o0 {
 		s0 = 1
@@ -85,15 +93,14 @@ func TrucoBeats(mHand, oHand Hand, m Card) int {
 //
 // Returns false if either player's play violates these strategies (unreasonable/wasteful play).
 func IsReasonablyPlayed(mHand, oHand Hand, m Card) bool {
-	var o0, o1, o2, m0, m1, m2 uint8
+	r := RulesFor(m)
+	return isReasonablyPlayedRanks(handRanks(r, mHand), handRanks(r, oHand))
+}
 
-	if m == NO_CARD {
-		o0, o1, o2 = oHand[0].Truco(), oHand[1].Truco(), oHand[2].Truco()
-		m0, m1, m2 = mHand[0].Truco(), mHand[1].Truco(), mHand[2].Truco()
-	} else {
-		o0, o1, o2 = oHand[0].TrucoUY(m), oHand[1].TrucoUY(m), oHand[2].TrucoUY(m)
-		m0, m1, m2 = mHand[0].TrucoUY(m), mHand[1].TrucoUY(m), mHand[2].TrucoUY(m)
-	}
+// IsReasonablyPlayed, from the truco ranks of the cards of each hand
+func isReasonablyPlayedRanks(mRanks, oRanks [3]uint8) bool {
+	m0, m1, m2 := mRanks[0], mRanks[1], mRanks[2]
+	o0, o1, o2 := oRanks[0], oRanks[1], oRanks[2]
 
 	// Round 0: mHand plays first, oHand plays second (can strategize)
 	if o0 > m0 {
@@ -164,16 +171,13 @@ func IsReasonablyPlayed(mHand, oHand Hand, m Card) bool {
 // plays the hand against all other hands, in all possible permutations.
 // counts times it wins, minus losses. Normalizes result to a percent.
 // range of score = (0 to 1)
+//
+// Opponent hands that share the same truco ranks play the same,
+// so we only simulate one of each class (see TrucoClasses).
 func (mHand Hand) TrucoStrength() float32 {
 	mPerms := math.PermutationsRaw(mHand, 3)
 	aCards := CardsExcluding(ALL_CARDS, mHand)
-	oPerms := math.PermutationsRaw(aCards, 3)
-	var score int
-	for mH := range mPerms {
-		for oH := range oPerms {
-			score += TrucoBeats(Hand(mPerms[mH]), Hand(oPerms[oH]), NO_CARD)
-		}
-	}
+	score := trucoScore(mPerms, TrucoClasses(aCards, NO_CARD), ArgentineRules{})
 	return float32(score) / (math.PickC(37, 3) * 36.0)
 }
 
@@ -185,194 +189,147 @@ func (mHand Hand) TrucoStrength() float32 {
 //
 // bench = 330 ms
 func (mHand Hand) TrucoStrengthUY() float32 {
-	var c int
+	return trucoStrengthTurned(mHand, func(m Card) Ruleset { return UruguayanRules{M: m} })
+}
+
+// Strength of a hand in a variant that turns up a card (muestra, vira),
+// averaged over every card that can be turned up: rules gives the ruleset of each.
+func trucoStrengthTurned(mHand Hand, rules func(Card) Ruleset) float32 {
 	mPerms := math.PermutationsRaw(mHand, 3)
 	aCards := CardsExcluding(ALL_CARDS, mHand)
-	oPerms := math.PermutationsRaw(aCards, 3)
-	pPerms := math.PermutationsRaw(aCards, 1) // muestra
-
-	var score int
-	for mH := range mPerms {
-		for oH := range oPerms {
-			for m := range pPerms {
-				if oPerms[oH][0] == pPerms[m][0] || oPerms[oH][1] == pPerms[m][0] || oPerms[oH][2] == pPerms[m][0] {
-					// muestra may be in oHand
-					continue // muestra should be unique
-				} else {
-					score += TrucoBeats(Hand(mPerms[mH]), Hand(oPerms[oH]), pPerms[m][0])
-					c++
-				}
+
+	var score, c float64
+	for _, m := range aCards {
+		// muestra should be unique
+		r := rules(m)
+		oClasses := trucoClasses(CardsExcluding(aCards, []Card{m}), r)
+		score += trucoScore(mPerms, oClasses, r)
+		for _, o := range oClasses {
+			c += o.Weight * 6 * float64(len(mPerms))
+		}
+	}
+	return float32(score / c)
+}
+
+// Wins of every permutation of mHand against every permutation
+// of every weighted opponent hand
+func trucoScore(mPerms [][]Card, oHands []WeightedHand, r Ruleset) float64 {
+	var score float64
+	for _, o := range oHands {
+		oPerms := math.PermutationsRaw(o.Hand, 3)
+		var wins int
+		for mH := range mPerms {
+			for oH := range oPerms {
+				wins += r.Beats(Hand(mPerms[mH]), Hand(oPerms[oH]))
 			}
 		}
+		score += float64(wins) * o.Weight
 	}
-	return float32(score) / float32(c)
+	return score
 }
 
-// TrucoStrengthStats calculates strength statistics for a mHand by simulating
+// StrengthStats calculates strength statistics for a mHand by simulating
 // all possible permutations against all possible opponent hands, given known info.
-// Helps players identify best permutation to play
-//
-// For Argentinian Truco.
+// Helps players identify best permutation to play.
 //
 // Parameters:
+//   - r: rules of the variant played
 //   - kCards: Cards held by the opponent (already played by them, in the order played).
 //   - oCards: Known cards the opponent does not hold (e.g., cards played by other players).
 //   - envido: Known envido of the opponent, helps exclude impossible hands.
 //   - isMHandFirst: boolean controling who plays first in round 0
-//   - hasStrategy: if true, checks IsReasonablyPlayed, discards permutations that are unreasonably played
+//   - model: how the opponent plays its hand against each permutation (see OpponentModel)
 //
 // Notes:
-//   - envido range as fsm envido (0-33: known, 100-199: envido range, 200: unknown flor, 201-254: flor, 255: unknown)
+//   - envido values as Ruleset.Envido
+//   - if the opponent didn't declare flor when they could, use EnvidoDeclined
 //
 // Returns TrucoStats containing the overall strength and per-permutation breakdown.
-func (mHand Hand) TrucoStrengthStats(kCards, oCards []Card, envido uint8, isMHandFirst, hasStrategy bool) TrucoStats {
-	mPerms := math.Permutations(mHand, 3)
-	aCards := CardsExcluding(ALL_CARDS, append(mHand, oCards...))
-	oPerms := math.Permutations(aCards, 3)
-	mEnvido := mHand.Envido()
-
-	isReasonablyPlayed := true
-	var eScore, eCount, cScore, totScore, cCount, totCount int
-	perms := make([]Hand, 0, 6)
-	winsPerm := make([]float32, 0, 6)
-	counts := make([]float32, 0, 6)
-
-	for mH := range mPerms {
-		cScore, cCount = 0, 0
-		for oH := range oPerms {
-			if envido != 255 {
-				oEnvido := Hand(oH).Envido()
-				if envido > 99 && oEnvido > (envido-100) { // range
-					continue
-				} else if envido < 99 && oEnvido != envido { // concrete
-					continue
-				}
-			}
+func (mHand Hand) StrengthStats(r Ruleset, kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool, model OpponentModel) TrucoStats {
+	return mHand.strengthStats(r, math.PermutationsRaw(mHand, 3), kCards, oCards, envido, isMHandFirst, model)
+}
 
-			if Hand(oH).HasAllInPlace(kCards) {
-				if hasStrategy {
-					if isMHandFirst {
-						isReasonablyPlayed = IsReasonablyPlayed(mH, oH, NO_CARD)
-					} else {
-						isReasonablyPlayed = IsReasonablyPlayed(oH, mH, NO_CARD)
-					}
-				}
+// StrengthStats once the cards of mPlayed are played, in the order played:
+// only the permutations of mHand that start with mPlayed can still happen.
+func (mHand Hand) StrengthStatsPlayed(r Ruleset, mPlayed, kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool, model OpponentModel) TrucoStats {
+	var mPerms [][]Card
+	for _, perm := range math.PermutationsRaw(mHand, 3) {
+		if slices.Equal(perm[:len(mPlayed)], mPlayed) {
+			mPerms = append(mPerms, perm)
+		}
+	}
+	return mHand.strengthStats(r, mPerms, kCards, oCards, envido, isMHandFirst, model)
+}
 
-				if isReasonablyPlayed {
-					cScore += TrucoBeats(Hand(mH), Hand(oH), NO_CARD)
-					cCount++
-				}
-				eScore += EnvidoBeats(mEnvido, Hand(oH).Envido(), isMHandFirst)
-				eCount++
-			}
+// StrengthStats for the permutations mPerms of mHand
+func (mHand Hand) strengthStats(r Ruleset, mPerms [][]Card, kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool, model OpponentModel) TrucoStats {
+	mEnvido := r.Envido(mHand)
+
+	var eScore, eCount int
+	winsPerm := make([]float32, len(mPerms))
+	counts := make([]float32, len(mPerms))
+
+	// opponent hands that are the same under a suit symmetry are simulated once (see canon.go)
+	syms := fixingSymmetries(slices.Concat(mHand, kCards), oCards, r)
+	for _, class := range canonicalClasses(possibleOHands(r, mHand, kCards, oCards, envido), syms) {
+		weight := int(class.Weight)
+		for i := range mPerms {
+			wins, count := model.Versus(mPerms[i], class.Hand, kCards, r, isMHandFirst)
+			winsPerm[i] += float32(wins * weight)
+			counts[i] += float32(count * weight)
 		}
-		perms = append(perms, mH)
-		winsPerm = append(winsPerm, float32(cScore))
-		counts = append(counts, float32(cCount))
-		totScore += cScore
-		totCount += cCount
+		eScore += EnvidoBeats(mEnvido, r.Envido(class.Hand), isMHandFirst) * weight
+		eCount += weight
 	}
 
-	return finalTrucoStrengthStats(rawTrucoStats{
-		TotCount: totCount,
-		TotScore: totScore,
-		WinsPerm: winsPerm,
-		Counts:   counts,
-		MHand:    mHand,
-		Perms:    perms,
-		MEnvido:  mEnvido,
-		EScore:   eScore,
-		ECount:   eCount,
-	})
+	return finalTrucoStrengthStats(newRawTrucoStats(mHand, mPerms, winsPerm, counts, mEnvido, eScore, eCount))
 }
 
-// TrucoStrengthStatsUY calculates strength statistics for a mHand by simulating
-// all possible permutations against all possible opponent hands, given known info.
-// Helps players identify best permutation to play.
-//
-// For Uruguayan Truco.
-//
-// Parameters:
-//   - kCards: Cards held by the opponent (already played by them, in the order played).
-//   - oCards: Known cards the opponent does not hold (e.g., cards played by other players). First card is 'muestra'.
-//   - envido: Known envido of the opponent, helps exclude impossible hands.
-//   - isMHandFirst: boolean controling who plays first in round 0
-//   - hasStrategy: if true, checks IsReasonablyPlayed, discards permutations that are unreasonably played
+// TrucoStrengthStats is StrengthStats for argentinian truco.
 //
 // Notes:
-//   - envido range as fsm envido (0-33: known, 100-199: envido range, 200: unknown flor, 201-254: flor, 255: unknown)
+//   - envido values as Hand.Envido
+func (mHand Hand) TrucoStrengthStats(kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool, model OpponentModel) TrucoStats {
+	return mHand.StrengthStats(ArgentineRules{}, kCards, oCards, envido, isMHandFirst, model)
+}
+
+// TrucoStrengthStatsUY is StrengthStats for uruguayan truco.
+// First card of oCards is 'muestra'.
 //
-// Returns TrucoStats containing the overall strength and per-permutation breakdown.
-func (mHand Hand) TrucoStrengthStatsUY(kCards, oCards []Card, envido uint8, isMHandFirst, hasStrategy bool) TrucoStats {
-	mPerms := math.PermutationsRaw(mHand, 3)
-	aCards := CardsExcluding(ALL_CARDS, append(mHand, oCards...))
-	oPerms := math.PermutationsRaw(aCards, 3)
-	muestra := oCards[0]
-	mEnvido := mHand.EnvidoUY(muestra)
-
-	isReasonablyPlayed := true
-	var eScore, eCount, cScore, totScore, cCount, totCount int
-	perms := make([]Hand, 0, 6)
-	winsPerm := make([]float32, 0, 6)
-	counts := make([]float32, 0, 6)
-
-	for mH := range mPerms {
-		cScore, cCount = 0, 0
-		for oH := range oPerms {
-			if oPerms[oH][0] == muestra || oPerms[oH][1] == muestra || oPerms[oH][2] == muestra {
-				continue // muestra should be unique
-			}
+// Notes:
+//   - envido values as Hand.EnvidoUY (flor is 220-247)
+func (mHand Hand) TrucoStrengthStatsUY(kCards, oCards []Card, envido EnvidoConstraint, isMHandFirst bool, model OpponentModel) TrucoStats {
+	return mHand.StrengthStats(UruguayanRules{M: oCards[0]}, kCards, oCards[1:], envido, isMHandFirst, model)
+}
 
-			oEnvido := Hand(oPerms[oH]).EnvidoUY(muestra)
-			if envido == 255 { // didnt declare anything
-				if oEnvido > 200 { // only filter out flor
-					continue
-				}
-			} else if envido == 200 { // declare unknown flor
-				if oEnvido < 200 || oEnvido == 255 { // filter out non-flor
-					continue
-				}
-			} else if envido < 99 { // declare concrete envido
-				if oEnvido != envido {
-					continue
-				}
-			} else if envido < 199 { // declare range envido 'son buenas'
-				if oEnvido > (envido - 100) { // range
-					continue
-				}
-			} else { // known flor
-				if oEnvido != envido {
-					continue
-				}
-			}
+// Hands the opponent could hold, given what we know.
+// Parameters as in StrengthStats.
+func possibleOHands(r Ruleset, mHand Hand, kCards, oCards []Card, envido EnvidoConstraint) []Hand {
+	aCards := CardsExcluding(r.Deck(), append(slices.Clone(mHand), oCards...))
+	oHands := make([]Hand, 0)
 
-			if Hand(oPerms[oH]).HasAllInPlace(kCards) {
-				if hasStrategy {
-					if isMHandFirst {
-						isReasonablyPlayed = IsReasonablyPlayed(mPerms[mH], oPerms[oH], muestra)
-					} else {
-						isReasonablyPlayed = IsReasonablyPlayed(oPerms[oH], mPerms[mH], muestra)
-					}
-				}
+	for oH := range math.Combinations(aCards, 3) {
+		oHand := Hand(oH)
+		if !oHand.HasAll(kCards) {
+			continue
+		}
 
-				if isReasonablyPlayed {
-					cScore += TrucoBeats(Hand(mPerms[mH]), Hand(oPerms[oH]), muestra)
-					cCount++
-				}
-				eScore += EnvidoBeats(mEnvido, oEnvido, isMHandFirst)
-				eCount++
-			}
+		if envido.Allows(r.Envido(oHand)) {
+			oHands = append(oHands, oHand)
 		}
-		perms = append(perms, mPerms[mH])
-		winsPerm = append(winsPerm, float32(cScore))
-		counts = append(counts, float32(cCount))
-		totScore += cScore
-		totCount += cCount
 	}
+	return oHands
+}
 
-	return finalTrucoStrengthStats(rawTrucoStats{
-		TotCount: totCount,
-		TotScore: totScore,
+// Collects the results of a simulation, per permutation of mHand
+func newRawTrucoStats(mHand Hand, mPerms [][]Card, winsPerm, counts []float32, mEnvido uint8, eScore, eCount int) rawTrucoStats {
+	perms := make([]Hand, 0, len(mPerms))
+	for _, mH := range mPerms {
+		perms = append(perms, mH)
+	}
+	return rawTrucoStats{
+		TotCount: int(math.Sum(counts)),
+		TotScore: int(math.Sum(winsPerm)),
 		WinsPerm: winsPerm,
 		Counts:   counts,
 		MHand:    mHand,
@@ -380,7 +337,7 @@ func (mHand Hand) TrucoStrengthStatsUY(kCards, oCards []Card, envido uint8, isMH
 		MEnvido:  mEnvido,
 		EScore:   eScore,
 		ECount:   eCount,
-	})
+	}
 }
 
 type rawTrucoStats struct {
@@ -418,21 +375,26 @@ func finalTrucoStrengthStats(rawStats rawTrucoStats) TrucoStats {
 		}
 	} else {
 		strengthAll = 0
-		strengthsPermAbs = []float32{0, 0, 0, 0, 0, 0}
-		strengthsPermRel = []float32{0, 0, 0, 0, 0, 0}
-		strengthsPermLoss = []float32{0, 0, 0, 0, 0, 0}
+		strengthsPermAbs = make([]float32, len(rawStats.Perms))
+		strengthsPermRel = make([]float32, len(rawStats.Perms))
+		strengthsPermLoss = make([]float32, len(rawStats.Perms))
 	}
 
 	for pos := range 3 {
 		posScoreArr := make([]float32, 0, 3)
 		for _, mCard := range rawStats.MHand {
 			var pScore float32
+			var pCount int
 			for iPerm, perm := range rawStats.Perms {
 				if mCard == perm[pos] {
 					pScore += strengthsPermAbs[iPerm]
+					pCount++
 				}
 			}
-			posScoreArr = append(posScoreArr, pScore/2)
+			if pCount > 0 {
+				pScore /= float32(pCount)
+			}
+			posScoreArr = append(posScoreArr, pScore)
 		}
 		strengthsPosition = append(strengthsPosition, posScoreArr)
 	}